@@ -0,0 +1,500 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// Kinds of breaking changes.
+const (
+	MessageRemoved     = "MESSAGE_REMOVED"
+	FieldRemoved       = "FIELD_REMOVED"
+	FieldRenamed       = "FIELD_RENAMED"
+	FieldNumberChanged = "FIELD_NUMBER_CHANGED"
+	FieldTypeChanged   = "FIELD_TYPE_CHANGED"
+	FieldLabelChanged  = "FIELD_LABEL_CHANGED"
+	FieldJsonChanged   = "FIELD_JSON_NAME_CHANGED"
+	EnumRemoved        = "ENUM_REMOVED"
+	EnumValueRemoved   = "ENUM_VALUE_REMOVED"
+	EnumValueChanged   = "ENUM_VALUE_NUMBER_CHANGED"
+	ServiceRemoved     = "SERVICE_REMOVED"
+	RpcRemoved         = "RPC_REMOVED"
+	RpcRenamed         = "RPC_RENAMED"
+	RpcTypeChanged     = "RPC_TYPE_CHANGED"
+	HttpBindingChanged = "HTTP_BINDING_CHANGED"
+)
+
+// BreakingChange describes a change between two generated contracts that breaks existing clients.
+type BreakingChange struct {
+	// One of the kinds above, e.g.: FIELD_NUMBER_CHANGED.
+	Kind string `json:"kind"`
+	// Fully qualified name of the affected element, e.g.: 'bookstore.Book.title'.
+	Element string `json:"element"`
+	// Human-readable description of the change.
+	Description string `json:"description"`
+	// Wire-incompatible: messages serialized by old clients are not understood by new servers (or vice versa).
+	Wire bool `json:"wire"`
+	// Source-incompatible: code generated from the old contract does not compile against the new one (this includes
+	// the JSON mapping and the HTTP bindings for REST clients).
+	Source bool `json:"source"`
+}
+
+// BreakingChangeReport is the machine-readable output of the breaking change detector.
+type BreakingChangeReport struct {
+	BreakingChanges []*BreakingChange `json:"breaking_changes"`
+}
+
+// RunBreakingChangeDetector is the main function of the breaking change detector. It generates the contract for the
+// new OpenAPI description and compares it with the old contract, which is either an OpenAPI description or a
+// FileDescriptorSet (.descr or .pb) that was generated before. Returns the exit code: 0 if there are no breaking
+// changes, 1 if there are breaking changes, 2 if an error occurred.
+func RunBreakingChangeDetector(args []string, stdout io.Writer, stderr io.Writer) int {
+	flags := flag.NewFlagSet("breaking", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	oldPath := flags.String("old", "", "the old OpenAPI description or FileDescriptorSet (.descr or .pb)")
+	newPath := flags.String("new", "", "the new OpenAPI description")
+	packageName := flags.String("package", "", "the package name of the generated contract (defaults to the name of the new OpenAPI description)")
+	format := flags.String("format", "text", "the output format: 'text' or 'json'")
+	if err := flags.Parse(args); err != nil {
+		return 2
+	}
+	if *oldPath == "" || *newPath == "" {
+		fmt.Fprintln(stderr, "both -old and -new are required")
+		return 2
+	}
+
+	if *packageName == "" {
+		name, err := resolvePackageName(strings.TrimSuffix(*newPath, filepath.Ext(*newPath)))
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		*packageName = name
+	}
+
+	oldFdSet, err := loadContract(*oldPath, *packageName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+	newFdSet, err := loadContract(*newPath, *packageName)
+	if err != nil {
+		fmt.Fprintln(stderr, err)
+		return 2
+	}
+
+	changes := DetectBreakingChanges(oldFdSet, newFdSet)
+	switch *format {
+	case "json":
+		b, err := json.MarshalIndent(&BreakingChangeReport{BreakingChanges: changes}, "", "  ")
+		if err != nil {
+			fmt.Fprintln(stderr, err)
+			return 2
+		}
+		fmt.Fprintln(stdout, string(b))
+	case "text":
+		fmt.Fprint(stdout, FormatBreakingChanges(changes))
+	default:
+		fmt.Fprintln(stderr, "unknown format: "+*format)
+		return 2
+	}
+
+	if len(changes) > 0 {
+		return 1
+	}
+	return 0
+}
+
+// FormatBreakingChanges returns a human-readable representation of 'changes'.
+func FormatBreakingChanges(changes []*BreakingChange) string {
+	if len(changes) == 0 {
+		return "No breaking changes.\n"
+	}
+	var b strings.Builder
+	for _, change := range changes {
+		var incompatibilities []string
+		if change.Wire {
+			incompatibilities = append(incompatibilities, "wire")
+		}
+		if change.Source {
+			incompatibilities = append(incompatibilities, "source")
+		}
+		fmt.Fprintf(&b, "%s: %s (%s-incompatible): %s\n",
+			change.Element, change.Kind, strings.Join(incompatibilities, ", "), change.Description)
+	}
+	fmt.Fprintf(&b, "%d breaking change(s).\n", len(changes))
+	return b.String()
+}
+
+// Returns the FileDescriptorSet for 'path'. It is either read directly from a .descr or .pb file, or it is
+// generated from the OpenAPI description at 'path'.
+func loadContract(path string, packageName string) (*dpb.FileDescriptorSet, error) {
+	switch filepath.Ext(path) {
+	case ".descr", ".pb":
+		b, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		fdSet := &dpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(b, fdSet); err != nil {
+			return nil, err
+		}
+		return fdSet, nil
+	}
+
+	document, err := readOpenAPIDocument(path)
+	if err != nil {
+		return nil, err
+	}
+	surfaceModel, err := buildSurfaceModel(document, path)
+	if err != nil {
+		return nil, err
+	}
+	renderer := NewRenderer(surfaceModel)
+	renderer.Package = packageName
+	renderer.Document = document
+	return renderer.runFileDescriptorSetGenerator()
+}
+
+// DetectBreakingChanges compares the contracts 'oldFdSet' and 'newFdSet' and returns all changes that break clients
+// of the old contract. Files are matched by name, elements inside of the files by their fully qualified name.
+func DetectBreakingChanges(oldFdSet *dpb.FileDescriptorSet, newFdSet *dpb.FileDescriptorSet) []*BreakingChange {
+	d := &breakingChangeDetector{changes: make([]*BreakingChange, 0)}
+	newFiles := make(map[string]*dpb.FileDescriptorProto)
+	for _, fd := range newFdSet.GetFile() {
+		newFiles[fd.GetName()] = fd
+	}
+
+	for _, oldFd := range oldFdSet.GetFile() {
+		if isDependencyFile(oldFd.GetName()) {
+			continue
+		}
+		newFd := newFiles[oldFd.GetName()]
+		if newFd == nil {
+			newFd = &dpb.FileDescriptorProto{} // Everything got removed.
+		}
+		prefix := oldFd.GetPackage()
+		d.compareMessages(prefix, oldFd.MessageType, newFd.MessageType)
+		d.compareEnums(prefix, oldFd.EnumType, newFd.EnumType)
+		d.compareServices(prefix, oldFd.Service, newFd.Service)
+	}
+	return d.changes
+}
+
+type breakingChangeDetector struct {
+	changes []*BreakingChange
+}
+
+func (d *breakingChangeDetector) add(kind string, element string, wire bool, source bool, format string, args ...interface{}) {
+	d.changes = append(d.changes, &BreakingChange{
+		Kind:        kind,
+		Element:     element,
+		Description: fmt.Sprintf(format, args...),
+		Wire:        wire,
+		Source:      source,
+	})
+}
+
+func (d *breakingChangeDetector) compareMessages(prefix string, oldMessages []*dpb.DescriptorProto, newMessages []*dpb.DescriptorProto) {
+	for _, oldMessage := range oldMessages {
+		if oldMessage.GetOptions().GetMapEntry() {
+			continue // Changes of maps are detected on the field that uses the map.
+		}
+		fullName := prefix + "." + oldMessage.GetName()
+		newMessage := findMessage(newMessages, oldMessage.GetName())
+		if newMessage == nil {
+			d.add(MessageRemoved, fullName, false, true, "message was removed")
+			continue
+		}
+		d.compareFields(fullName, oldMessage, newMessage)
+		d.compareMessages(fullName, oldMessage.NestedType, newMessage.NestedType)
+		d.compareEnums(fullName, oldMessage.EnumType, newMessage.EnumType)
+	}
+}
+
+func (d *breakingChangeDetector) compareFields(messageName string, oldMessage *dpb.DescriptorProto, newMessage *dpb.DescriptorProto) {
+	for _, oldField := range oldMessage.Field {
+		fullName := messageName + "." + oldField.GetName()
+
+		newField := findFieldByName(newMessage, oldField.GetName())
+		if newField == nil {
+			newField = findFieldByNumber(newMessage, oldField.GetNumber())
+			switch {
+			case newField == nil && isReservedNumber(newMessage, oldField.GetNumber()):
+				d.add(FieldRemoved, fullName, false, true, "field %d was removed, its number is reserved", oldField.GetNumber())
+				continue
+			case newField == nil:
+				d.add(FieldRemoved, fullName, true, true, "field %d was removed without reserving its number", oldField.GetNumber())
+				continue
+			case findFieldByName(oldMessage, newField.GetName()) != nil:
+				d.add(FieldRemoved, fullName, true, true, "field %d was removed, its number is reused by '%s'", oldField.GetNumber(), newField.GetName())
+				continue
+			}
+			d.add(FieldRenamed, fullName, false, true, "field %d was renamed to '%s'", oldField.GetNumber(), newField.GetName())
+		} else if newField.GetNumber() != oldField.GetNumber() {
+			d.add(FieldNumberChanged, fullName, true, false, "field number changed from %d to %d", oldField.GetNumber(), newField.GetNumber())
+		}
+
+		// The names of map entries depend on the name of the field, so the types of their keys and values are compared.
+		oldEntry, newEntry := findMapEntry(oldMessage, oldField), findMapEntry(newMessage, newField)
+		if oldEntry != nil && newEntry != nil {
+			if oldType, newType := describeMapType(oldEntry), describeMapType(newEntry); oldType != newType {
+				d.add(FieldTypeChanged, fullName, true, true, "type changed from %s to %s", oldType, newType)
+			}
+		} else if oldType, newType := describeFieldType(oldField), describeFieldType(newField); oldType != newType {
+			d.add(FieldTypeChanged, fullName, true, true, "type changed from %s to %s", oldType, newType)
+		} else if oldField.GetLabel() != newField.GetLabel() {
+			d.add(FieldLabelChanged, fullName, true, true, "label changed from %s to %s", describeLabel(oldField), describeLabel(newField))
+		}
+
+		// The JSON mapping of REST clients uses the JSON name, which can change while the field keeps its name.
+		if oldJsonName, newJsonName := describeJsonName(oldField), describeJsonName(newField); oldJsonName != newJsonName {
+			d.add(FieldJsonChanged, fullName, false, true, "JSON name changed from '%s' to '%s'", oldJsonName, newJsonName)
+		}
+	}
+}
+
+func (d *breakingChangeDetector) compareEnums(prefix string, oldEnums []*dpb.EnumDescriptorProto, newEnums []*dpb.EnumDescriptorProto) {
+	for _, oldEnum := range oldEnums {
+		fullName := prefix + "." + oldEnum.GetName()
+		var newEnum *dpb.EnumDescriptorProto
+		for _, e := range newEnums {
+			if e.GetName() == oldEnum.GetName() {
+				newEnum = e
+			}
+		}
+		if newEnum == nil {
+			d.add(EnumRemoved, fullName, false, true, "enum was removed")
+			continue
+		}
+
+		for _, oldValue := range oldEnum.Value {
+			var newValue *dpb.EnumValueDescriptorProto
+			for _, v := range newEnum.Value {
+				if v.GetName() == oldValue.GetName() {
+					newValue = v
+				}
+			}
+			valueName := fullName + "." + oldValue.GetName()
+			if newValue == nil {
+				d.add(EnumValueRemoved, valueName, false, true, "enum value %d was removed", oldValue.GetNumber())
+			} else if newValue.GetNumber() != oldValue.GetNumber() {
+				d.add(EnumValueChanged, valueName, true, false, "enum value number changed from %d to %d", oldValue.GetNumber(), newValue.GetNumber())
+			}
+		}
+	}
+}
+
+func (d *breakingChangeDetector) compareServices(prefix string, oldServices []*dpb.ServiceDescriptorProto, newServices []*dpb.ServiceDescriptorProto) {
+	for _, oldService := range oldServices {
+		fullName := prefix + "." + oldService.GetName()
+		var newService *dpb.ServiceDescriptorProto
+		for _, s := range newServices {
+			if s.GetName() == oldService.GetName() {
+				newService = s
+			}
+		}
+		if newService == nil {
+			d.add(ServiceRemoved, fullName, true, true, "service was removed")
+			continue
+		}
+
+		for _, oldMethod := range oldService.Method {
+			methodName := fullName + "." + oldMethod.GetName()
+			oldBinding := describeHttpBinding(oldMethod)
+
+			newMethod := findMethod(newService, oldMethod.GetName())
+			if newMethod == nil {
+				// A RPC that has the same HTTP binding as before was most likely renamed.
+				renamed := false
+				for _, m := range newService.Method {
+					if oldBinding != "" && describeHttpBinding(m) == oldBinding && findMethod(oldService, m.GetName()) == nil {
+						d.add(RpcRenamed, methodName, true, true, "RPC was renamed to '%s'", m.GetName())
+						renamed = true
+					}
+				}
+				if !renamed {
+					d.add(RpcRemoved, methodName, true, true, "RPC was removed")
+				}
+				continue
+			}
+
+			if oldMethod.GetInputType() != newMethod.GetInputType() {
+				d.add(RpcTypeChanged, methodName, true, true, "request type changed from %s to %s", oldMethod.GetInputType(), newMethod.GetInputType())
+			}
+			if oldMethod.GetOutputType() != newMethod.GetOutputType() {
+				d.add(RpcTypeChanged, methodName, true, true, "response type changed from %s to %s", oldMethod.GetOutputType(), newMethod.GetOutputType())
+			}
+			if newBinding := describeHttpBinding(newMethod); oldBinding != newBinding {
+				d.add(HttpBindingChanged, methodName, false, true, "HTTP binding changed from '%s' to '%s'", oldBinding, newBinding)
+			}
+		}
+	}
+}
+
+// Returns a string representation of the google.api.http option of 'method', e.g.: 'GET /books/{id} body:"*"'.
+func describeHttpBinding(method *dpb.MethodDescriptorProto) string {
+	if method.GetOptions() == nil || !proto.HasExtension(method.GetOptions(), annotations.E_Http) {
+		return ""
+	}
+	ext, err := proto.GetExtension(method.GetOptions(), annotations.E_Http)
+	if err != nil {
+		return ""
+	}
+	rule, ok := ext.(*annotations.HttpRule)
+	if !ok {
+		return ""
+	}
+
+	var binding string
+	switch pattern := rule.GetPattern().(type) {
+	case *annotations.HttpRule_Get:
+		binding = "GET " + pattern.Get
+	case *annotations.HttpRule_Put:
+		binding = "PUT " + pattern.Put
+	case *annotations.HttpRule_Post:
+		binding = "POST " + pattern.Post
+	case *annotations.HttpRule_Delete:
+		binding = "DELETE " + pattern.Delete
+	case *annotations.HttpRule_Patch:
+		binding = "PATCH " + pattern.Patch
+	case *annotations.HttpRule_Custom:
+		binding = pattern.Custom.GetKind() + " " + pattern.Custom.GetPath()
+	}
+	if rule.Body != "" {
+		binding += " body:\"" + rule.Body + "\""
+	}
+	if rule.ResponseBody != "" {
+		binding += " response_body:\"" + rule.ResponseBody + "\""
+	}
+	return binding
+}
+
+// Returns the type of 'field' as it would be written inside of a .proto. The type name is used for messages and
+// enums, the leading dot of fully qualified names is ignored.
+func describeFieldType(field *dpb.FieldDescriptorProto) string {
+	switch field.GetType() {
+	case dpb.FieldDescriptorProto_TYPE_MESSAGE, dpb.FieldDescriptorProto_TYPE_ENUM:
+		return strings.TrimPrefix(field.GetTypeName(), ".")
+	}
+	return strings.ToLower(strings.TrimPrefix(field.GetType().String(), "TYPE_"))
+}
+
+// Returns the type of the map with the entry 'entry' as it would be written inside of a .proto, e.g.:
+// 'map<string, int32>'.
+func describeMapType(entry *dpb.DescriptorProto) string {
+	key, value := findFieldByNumber(entry, 1), findFieldByNumber(entry, 2)
+	if key == nil || value == nil {
+		return "map"
+	}
+	return "map<" + describeFieldType(key) + ", " + describeFieldType(value) + ">"
+}
+
+// Returns the map entry that is the type of 'field' (a field of 'message') or nil if 'field' is not a map.
+func findMapEntry(message *dpb.DescriptorProto, field *dpb.FieldDescriptorProto) *dpb.DescriptorProto {
+	if field.GetType() != dpb.FieldDescriptorProto_TYPE_MESSAGE || field.GetLabel() != dpb.FieldDescriptorProto_LABEL_REPEATED {
+		return nil
+	}
+	typeName := field.GetTypeName()
+	entry := findMessage(message.NestedType, typeName[strings.LastIndex(typeName, ".")+1:])
+	if entry == nil || !entry.GetOptions().GetMapEntry() {
+		return nil
+	}
+	return entry
+}
+
+func describeLabel(field *dpb.FieldDescriptorProto) string {
+	return strings.ToLower(strings.TrimPrefix(field.GetLabel().String(), "LABEL_"))
+}
+
+func findMessage(messages []*dpb.DescriptorProto, name string) *dpb.DescriptorProto {
+	for _, m := range messages {
+		if m.GetName() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+func findFieldByName(message *dpb.DescriptorProto, name string) *dpb.FieldDescriptorProto {
+	for _, f := range message.Field {
+		if f.GetName() == name {
+			return f
+		}
+	}
+	return nil
+}
+
+func findFieldByNumber(message *dpb.DescriptorProto, number int32) *dpb.FieldDescriptorProto {
+	for _, f := range message.Field {
+		if f.GetNumber() == number {
+			return f
+		}
+	}
+	return nil
+}
+
+func findMethod(service *dpb.ServiceDescriptorProto, name string) *dpb.MethodDescriptorProto {
+	for _, m := range service.Method {
+		if m.GetName() == name {
+			return m
+		}
+	}
+	return nil
+}
+
+// Returns true if 'number' is inside of a reserved range of 'message'. The end of a reserved range is exclusive.
+func isReservedNumber(message *dpb.DescriptorProto, number int32) bool {
+	for _, r := range message.ReservedRange {
+		if number >= r.GetStart() && number < r.GetEnd() {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the JSON name of 'field': the option 'json_name' or else the lowerCamelCase name that protoc derives from the
+// name of the field (e.g.: 'photoUrls' for 'photo_urls').
+func describeJsonName(field *dpb.FieldDescriptorProto) string {
+	if field.JsonName != nil {
+		return field.GetJsonName()
+	}
+	var b strings.Builder
+	upper := false
+	for _, r := range field.GetName() {
+		if r == '_' {
+			upper = true
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,69 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"path/filepath"
+	"testing"
+)
+
+func TestBreakingChangeDetector(t *testing.T) {
+	args := []string{"-old", "testfiles/breaking/old.yaml", "-new", "testfiles/breaking/new.yaml", "-package", "breaking"}
+
+	for format, goldenFileName := range map[string]string{
+		"text": "goldstandard/breaking.txt",
+		"json": "goldstandard/breaking.json",
+	} {
+		var stdout, stderr bytes.Buffer
+		exitCode := RunBreakingChangeDetector(append(args, "-format", format), &stdout, &stderr)
+		if exitCode != 1 {
+			t.Errorf("Expected exit code 1 for format %s, got %d: %s", format, exitCode, stderr.String())
+		}
+		checkContents(t, stdout.String(), goldenFileName)
+	}
+}
+
+func TestBreakingChangeDetectorWithAbsolutePaths(t *testing.T) {
+	oldPath, err := filepath.Abs("testfiles/breaking/old.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	newPath, err := filepath.Abs("testfiles/breaking/new.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := []string{"-old", oldPath, "-new", newPath, "-package", "breaking"}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := RunBreakingChangeDetector(args, &stdout, &stderr)
+	if exitCode != 1 {
+		t.Errorf("Expected exit code 1, got %d: %s", exitCode, stderr.String())
+	}
+	checkContents(t, stdout.String(), "goldstandard/breaking.txt")
+}
+
+func TestBreakingChangeDetectorWithoutChanges(t *testing.T) {
+	args := []string{"-old", "testfiles/breaking/old.yaml", "-new", "testfiles/breaking/old.yaml", "-package", "breaking"}
+
+	var stdout, stderr bytes.Buffer
+	exitCode := RunBreakingChangeDetector(args, &stdout, &stderr)
+	if exitCode != 0 {
+		t.Errorf("Expected exit code 0, got %d: %s", exitCode, stderr.String())
+	}
+	if stdout.String() != "No breaking changes.\n" {
+		t.Errorf("Unexpected output: %s", stdout.String())
+	}
+}
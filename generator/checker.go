@@ -25,6 +25,12 @@ type GrpcChecker struct {
 	document *openapiv3.Document
 	// The messages that are displayed to the user with information of what is not being processed by the generator.
 	messages []*plugins.Message
+	// If true, the constraints that are attached to the fields as validation rules are not reported.
+	ValidateRules bool
+	// If true, tags are used to group the operations into services and are not reported.
+	ServicePerTag bool
+	// The strategy for optional fields (see: Renderer.OptionalFields). Unless it is set, 'nullable' is reported.
+	OptionalFields string
 }
 
 // Creates a new checker.
@@ -40,7 +46,7 @@ func (c *GrpcChecker) Run() []*plugins.Message {
 
 // Analyzes the root object.
 func (c *GrpcChecker) analyzeOpenAPIDocument() {
-	fields := getNotSupportedOpenAPIDocumentFields(c.document)
+	fields := getNotSupportedOpenAPIDocumentFields(c.document, c.ServicePerTag)
 	if len(fields) > 0 {
 		text := "Fields: " + strings.Join(fields, ", ") + " are not supported for Document with title: " + c.document.Info.Title
 		msg := constructMessage("DOCUMENTFIELDS", text, []string{"Document"})
@@ -91,6 +97,24 @@ func (c *GrpcChecker) analyzePaths() {
 	for _, pathItem := range c.document.Paths.Path {
 		c.analyzePathItem(pathItem)
 	}
+	c.analyzeOperationIds()
+}
+
+// Reports operationIds that are used by several operations. The generator renames all but the first operation
+// (see: getOperationIds).
+func (c *GrpcChecker) analyzeOperationIds() {
+	operationIds := getOperationIds(c.document)
+	for _, pair := range c.document.Paths.Path {
+		for _, operation := range getPathItemOperations(pair.Value) {
+			if operation == nil || operation.OperationId == "" || operationIds[operation] == operation.OperationId {
+				continue
+			}
+			text := "The operationId: " + operation.OperationId + " is used by several operations. The RPC and the " +
+				"messages of the operation at path: " + pair.Name + " are named after: " + operationIds[operation]
+			msg := constructMessage("OPERATIONID", text, []string{"Paths", pair.Name, "Operation", operation.OperationId})
+			c.messages = append(c.messages, msg)
+		}
+	}
 }
 
 // Analyzes one single path.
@@ -112,7 +136,7 @@ func (c *GrpcChecker) analyzePathItem(pair *openapiv3.NamedPathItem) {
 
 // Analyzes a single Operation.
 func (c *GrpcChecker) analyzeOperation(operation *openapiv3.Operation) {
-	fields := getNotSupportedOperationFields(operation)
+	fields := getNotSupportedOperationFields(operation, c.ServicePerTag)
 	if len(fields) > 0 {
 		text := "Fields:  " + strings.Join(fields, ", ") + " are not supported for operation: " + operation.OperationId
 		msg := constructMessage("OPERATIONFIELDS", text, []string{"Operation", operation.OperationId, "Callbacks"})
@@ -153,28 +177,43 @@ func (c *GrpcChecker) analyzeParameter(paramOrRef *openapiv3.ParameterOrReferenc
 // Analyzes the schema.
 func (c *GrpcChecker) analyzeSchema(identifier string, schemaOrReference *openapiv3.SchemaOrReference) {
 	if schema := schemaOrReference.GetSchema(); schema != nil {
-		fields := getNotSupportedSchemaFields(schema)
+		fields := getNotSupportedSchemaFields(schema, c.ValidateRules, c.OptionalFields)
 		if len(fields) > 0 {
 			text := "Fields: " + strings.Join(fields, ", ") + " are not supported for the schema: " + identifier
 			msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
 			c.messages = append(c.messages, msg)
 		}
 
-		if enum := schema.Enum; enum != nil {
+		if fields := getZeroBoundSchemaFields(schema, c.ValidateRules); len(fields) > 0 {
+			text := "Fields: " + strings.Join(fields, ", ") + " are validated against a bound of 0 for the schema: " +
+				identifier + ". A bound of 0 can not be told apart from a missing bound, so 'minimum: 0' and " +
+				"'maximum: 0' are only validated if they are exclusive."
+			msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
+			c.messages = append(c.messages, msg)
+		}
+
+		// A oneof can only hold a single variant, but the value of 'anyOf' might match several of them.
+		if len(schema.AnyOf) > 0 {
+			text := "Field: AnyOf is generated as oneof in .proto for schema: " + identifier + ". Only one of the " +
+				"matching subschemas can be set."
+			msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
+			c.messages = append(c.messages, msg)
+		}
+
+		// Only string and integer enums are generated as enums.
+		if enum := schema.Enum; enum != nil && !isEnumSchema(schema) {
 			text := "Field: Enum is not generated as enum in .proto for schema: " + identifier
 			msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
 			c.messages = append(c.messages, msg)
 		}
 
-		// Check for this: https://github.com/LorenzHW/gnostic-grpc/issues/3#issuecomment-509348357
-		if additionalProperties := schema.AdditionalProperties; additionalProperties != nil {
-			if schema := additionalProperties.GetSchemaOrReference().GetSchema(); schema != nil {
-				if schema.Type == "array" {
-					text := "Field: additionalProperties with type array is generated as empty message inside .proto."
-					msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
-					c.messages = append(c.messages, msg)
-				}
-			}
+		// The JSON mapping of enums uses the names of the enum values, not the strings of the OpenAPI description.
+		if isEnumSchema(schema) && schema.Type == "string" {
+			text := "Field: Enum of schema: " + identifier + " is generated as enum in .proto. The JSON mapping of " +
+				"gRPC-HTTP transcoding uses the names of the enum values (<ENUM>_<VALUE>) instead of the strings of " +
+				"the OpenAPI description."
+			msg := constructMessage("SCHEMAFIELDS", text, []string{identifier, "Schema"})
+			c.messages = append(c.messages, msg)
 		}
 
 		if items := schema.Items; items != nil {
@@ -215,13 +254,17 @@ func (c *GrpcChecker) analyzeResponse(pair *openapiv3.NamedResponseOrReference)
 // Analyzes a request body.
 func (c *GrpcChecker) analyzeRequestBody(pair *openapiv3.NamedRequestBodyOrReference) {
 	if requestBody := pair.Value.GetRequestBody(); requestBody != nil {
-		if requestBody.Required {
-			text := "Fields: Required are not supported for the request: " + pair.Name
-			msg := constructMessage("REQUESTBODYFIELDS", text, []string{"RequestBody", pair.Name})
-			c.messages = append(c.messages, msg)
-		}
-		for _, pair := range requestBody.Content.AdditionalProperties {
-			c.analyzeContent(pair)
+		for _, mediaType := range requestBody.Content.AdditionalProperties {
+			c.analyzeContent(mediaType)
+
+			// gRPC-HTTP transcoders only decode JSON into messages by default.
+			if isFormMediaType(mediaType.Name) {
+				text := "Media type: " + mediaType.Name + " of request body: " + pair.Name + " is generated as message " +
+					"with one field per form part (binary parts as bytes). The transcoder has to be configured to " +
+					"decode this media type (e.g.: with a custom marshaler for grpc-gateway)."
+				msg := constructMessage("MEDIATYPE", text, []string{"RequestBody", pair.Name, mediaType.Name})
+				c.messages = append(c.messages, msg)
+			}
 		}
 	}
 }
@@ -277,8 +320,9 @@ func getValidOperations(pathItem *openapiv3.PathItem) []*openapiv3.Operation {
 	return operations
 }
 
-// Returns fields that the won't be considered by the plugin for document.
-func getNotSupportedOpenAPIDocumentFields(document *openapiv3.Document) []string {
+// Returns fields that the won't be considered by the plugin for document. If 'servicePerTag' is set, tags are
+// supported.
+func getNotSupportedOpenAPIDocumentFields(document *openapiv3.Document, servicePerTag bool) []string {
 	fields := make([]string, 0)
 	if document == nil {
 		return fields
@@ -290,7 +334,7 @@ func getNotSupportedOpenAPIDocumentFields(document *openapiv3.Document) []string
 	if document.Security != nil {
 		fields = append(fields, "Security")
 	}
-	if document.Tags != nil {
+	if document.Tags != nil && !servicePerTag {
 		fields = append(fields, "Tags")
 	}
 	if document.ExternalDocs != nil {
@@ -305,9 +349,6 @@ func getNotSupportedParameterFields(parameter *openapiv3.Parameter) []string {
 	if parameter == nil {
 		return fields
 	}
-	if parameter.Required {
-		fields = append(fields, "Required")
-	}
 	if parameter.Deprecated {
 		fields = append(fields, "Deprecated")
 	}
@@ -336,24 +377,17 @@ func getNotSupportedParameterFields(parameter *openapiv3.Parameter) []string {
 	return fields
 }
 
-// Returns fields that the won't be considered by the plugin for schema.
-func getNotSupportedSchemaFields(schema *openapiv3.Schema) []string {
+// Returns fields that the won't be considered by the plugin for schema. If 'validateRules' is set, the constraints
+// that are translated into validation rules are supported. 'nullable' is supported if there is a strategy for
+// 'optionalFields'.
+func getNotSupportedSchemaFields(schema *openapiv3.Schema, validateRules bool, optionalFields string) []string {
 	fields := make([]string, 0)
 	if schema == nil {
 		return fields
 	}
-	if schema.Nullable {
+	if schema.Nullable && optionalFields == OptionalFieldsNone {
 		fields = append(fields, "Nullable")
 	}
-	if schema.Discriminator != nil {
-		fields = append(fields, "Discriminator")
-	}
-	if schema.ReadOnly {
-		fields = append(fields, "ReadOnly")
-	}
-	if schema.WriteOnly {
-		fields = append(fields, "WriteOnly")
-	}
 	if schema.Xml != nil {
 		fields = append(fields, "Xml")
 	}
@@ -369,38 +403,41 @@ func getNotSupportedSchemaFields(schema *openapiv3.Schema) []string {
 	if schema.Title != "" {
 		fields = append(fields, "Title")
 	}
+	// protoc-gen-validate has no rule for 'multipleOf', so it is not validated even if validateRules is set.
 	if schema.MultipleOf != 0 {
 		fields = append(fields, "MultipleOf")
 	}
-	if schema.Maximum != 0 {
-		fields = append(fields, "Maximum")
-	}
-	if schema.ExclusiveMaximum {
-		fields = append(fields, "ExclusiveMaximum")
-	}
-	if schema.Minimum != 0 {
-		fields = append(fields, "Minimum")
-	}
-	if schema.ExclusiveMinimum {
-		fields = append(fields, "ExclusiveMinimum")
-	}
-	if schema.MaxLength != 0 {
-		fields = append(fields, "MaxLength")
-	}
-	if schema.MinLength != 0 {
-		fields = append(fields, "MinLength")
-	}
-	if schema.Pattern != "" {
-		fields = append(fields, "Pattern")
-	}
-	if schema.MaxItems != 0 {
-		fields = append(fields, "MaxItems")
-	}
-	if schema.MinItems != 0 {
-		fields = append(fields, "MinItems")
-	}
-	if schema.UniqueItems {
-		fields = append(fields, "UniqueItems")
+	if !validateRules {
+		if schema.Maximum != 0 {
+			fields = append(fields, "Maximum")
+		}
+		if schema.ExclusiveMaximum {
+			fields = append(fields, "ExclusiveMaximum")
+		}
+		if schema.Minimum != 0 {
+			fields = append(fields, "Minimum")
+		}
+		if schema.ExclusiveMinimum {
+			fields = append(fields, "ExclusiveMinimum")
+		}
+		if schema.MaxLength != 0 {
+			fields = append(fields, "MaxLength")
+		}
+		if schema.MinLength != 0 {
+			fields = append(fields, "MinLength")
+		}
+		if schema.Pattern != "" {
+			fields = append(fields, "Pattern")
+		}
+		if schema.MaxItems != 0 {
+			fields = append(fields, "MaxItems")
+		}
+		if schema.MinItems != 0 {
+			fields = append(fields, "MinItems")
+		}
+		if schema.UniqueItems {
+			fields = append(fields, "UniqueItems")
+		}
 	}
 	if schema.MaxProperties != 0 {
 		fields = append(fields, "MaxProperties")
@@ -408,19 +445,6 @@ func getNotSupportedSchemaFields(schema *openapiv3.Schema) []string {
 	if schema.MinProperties != 0 {
 		fields = append(fields, "MinProperties")
 	}
-	if schema.Required != nil {
-		fields = append(fields, "Required")
-	}
-	if schema.AllOf != nil {
-		fields = append(fields, "AllOf")
-	}
-	if schema.OneOf != nil {
-		fields = append(fields, "OneOf")
-	}
-
-	if schema.AnyOf != nil {
-		fields = append(fields, "AnyOf")
-	}
 	if schema.Not != nil {
 		fields = append(fields, "Not")
 	}
@@ -430,6 +454,21 @@ func getNotSupportedSchemaFields(schema *openapiv3.Schema) []string {
 	return fields
 }
 
+// Returns the exclusive bounds of schema that are translated into validation rules with a bound of 0.
+func getZeroBoundSchemaFields(schema *openapiv3.Schema, validateRules bool) []string {
+	fields := make([]string, 0)
+	if !validateRules || schema == nil {
+		return fields
+	}
+	if schema.ExclusiveMinimum && schema.Minimum == 0 {
+		fields = append(fields, "ExclusiveMinimum")
+	}
+	if schema.ExclusiveMaximum && schema.Maximum == 0 {
+		fields = append(fields, "ExclusiveMaximum")
+	}
+	return fields
+}
+
 // Returns fields that the won't be considered by the plugin for mediaType.
 func getNotSupportedMediaTypeFields(mediaType *openapiv3.MediaType) []string {
 	fields := make([]string, 0)
@@ -448,13 +487,14 @@ func getNotSupportedMediaTypeFields(mediaType *openapiv3.MediaType) []string {
 	return fields
 }
 
-// Returns fields that the won't be considered by the plugin for operation.
-func getNotSupportedOperationFields(operation *openapiv3.Operation) []string {
+// Returns fields that the won't be considered by the plugin for operation. If 'servicePerTag' is set, tags are
+// supported.
+func getNotSupportedOperationFields(operation *openapiv3.Operation, servicePerTag bool) []string {
 	fields := make([]string, 0)
 	if operation == nil {
 		return fields
 	}
-	if operation.Tags != nil {
+	if operation.Tags != nil && !servicePerTag {
 		fields = append(fields, "Tags")
 	}
 	if operation.ExternalDocs != nil {
@@ -17,50 +17,43 @@ package generator
 import (
 	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
 	plugins "github.com/googleapis/gnostic/plugins"
-	"os/exec"
 	"testing"
 )
 
 func TestNewFeatureCheckerParameters(t *testing.T) {
 	input := "testfiles/parameters.yaml"
-	documentv3 := readOpenAPIBinary(input)
+	documentv3 := readOpenAPIDocumentForTest(t, input)
 
 	checker := NewGrpcChecker(documentv3)
 	messages := checker.Run()
 	expectedMessageTexts := []string{
 		"Fields: Explode are not supported for parameter: param2",
 		"Fields: Default are not supported for the schema: Items of param2",
-		"Field: Enum is not generated as enum in .proto for schema: Items of param2",
 		"Fields: Default are not supported for the schema: param4",
-		"Field: Enum is not generated as enum in .proto for schema: param4",
 	}
 	validateMessages(t, expectedMessageTexts, messages)
 }
 
 func TestFeatureCheckerRequestBodies(t *testing.T) {
 	input := "testfiles/requestBodies.yaml"
-	documentv3 := readOpenAPIBinary(input)
+	documentv3 := readOpenAPIDocumentForTest(t, input)
 
 	checker := NewGrpcChecker(documentv3)
 	messages := checker.Run()
 	expectedMessageTexts := []string{
-		"Fields: Required are not supported for the schema: Person",
 		"Fields: Example are not supported for the schema: name",
 		"Fields: Xml are not supported for the schema: photoUrls",
-		"Fields: Required are not supported for the request: RequestBody",
 	}
 	validateMessages(t, expectedMessageTexts, messages)
 }
 
 func TestFeatureCheckerResponses(t *testing.T) {
 	input := "testfiles/responses.yaml"
-	documentv3 := readOpenAPIBinary(input)
+	documentv3 := readOpenAPIDocumentForTest(t, input)
 
 	checker := NewGrpcChecker(documentv3)
 	messages := checker.Run()
 	expectedMessageTexts := []string{
-		"Fields: Required are not supported for the schema: Error",
-		"Fields: Required are not supported for the schema: Person",
 		"Fields: Example are not supported for the schema: name",
 		"Fields: Xml are not supported for the schema: photoUrls",
 	}
@@ -69,19 +62,126 @@ func TestFeatureCheckerResponses(t *testing.T) {
 
 func TestFeatureCheckerOther(t *testing.T) {
 	input := "testfiles/other.yaml"
-	documentv3 := readOpenAPIBinary(input)
+	documentv3 := readOpenAPIDocumentForTest(t, input)
 
 	checker := NewGrpcChecker(documentv3)
 	messages := checker.Run()
 	expectedMessageTexts := []string{
-		"Fields: Required are not supported for the schema: Person",
 		"Fields: Example are not supported for the schema: name",
 		"Fields: Xml are not supported for the schema: photoUrls",
-		"Field: additionalProperties with type array is generated as empty message inside .proto.",
 	}
 	validateMessages(t, expectedMessageTexts, messages)
 }
 
+func TestFeatureCheckerEnums(t *testing.T) {
+	input := "testfiles/enums.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	messages := checker.Run()
+	expectedMessageTexts := []string{
+		"Field: Enum of schema: Status is generated as enum in .proto. The JSON mapping of gRPC-HTTP transcoding " +
+			"uses the names of the enum values (<ENUM>_<VALUE>) instead of the strings of the OpenAPI description.",
+		"Field: Enum of schema: size is generated as enum in .proto. The JSON mapping of gRPC-HTTP transcoding " +
+			"uses the names of the enum values (<ENUM>_<VALUE>) instead of the strings of the OpenAPI description.",
+		"Field: Enum of schema: sort_order is generated as enum in .proto. The JSON mapping of gRPC-HTTP " +
+			"transcoding uses the names of the enum values (<ENUM>_<VALUE>) instead of the strings of the OpenAPI " +
+			"description.",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+}
+
+func TestFeatureCheckerOptionalFields(t *testing.T) {
+	input := "testfiles/optional.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	// Without a strategy for optional fields 'nullable' is dropped.
+	checker := NewGrpcChecker(documentv3)
+	messages := checker.Run()
+	enumMessage := "Field: Enum of schema: level is generated as enum in .proto. The JSON mapping of gRPC-HTTP " +
+		"transcoding uses the names of the enum values (<ENUM>_<VALUE>) instead of the strings of the OpenAPI description."
+	expectedMessageTexts := []string{
+		"Fields: Nullable are not supported for the schema: nickname",
+		enumMessage,
+		"Fields: Nullable are not supported for the schema: Nickname",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+
+	checker = NewGrpcChecker(documentv3)
+	checker.OptionalFields = OptionalFieldsWrappers
+	validateMessages(t, []string{enumMessage}, checker.Run())
+}
+
+func TestFeatureCheckerNamingDuplicate(t *testing.T) {
+	input := "testfiles/namingDuplicate.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	messages := checker.Run()
+	expectedMessageTexts := []string{
+		"The operationId: listPets is used by several operations. The RPC and the messages of the operation at path: /pets/search are named after: listPets2",
+		"The operationId: listPets is used by several operations. The RPC and the messages of the operation at path: /pets/find are named after: listPets3",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+}
+
+func TestFeatureCheckerPolymorphism(t *testing.T) {
+	input := "testfiles/polymorphism.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	messages := checker.Run()
+	expectedMessageTexts := []string{
+		"Field: AnyOf is generated as oneof in .proto for schema: Shape. Only one of the matching subschemas can be set.",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+}
+
+func TestFeatureCheckerValidateRules(t *testing.T) {
+	input := "testfiles/validate.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	checker.ValidateRules = true
+	messages := checker.Run()
+	expectedMessageTexts := []string{
+		"Fields: MultipleOf are not supported for the schema: weight",
+		"Fields: ExclusiveMinimum are validated against a bound of 0 for the schema: rating. A bound of 0 can not " +
+			"be told apart from a missing bound, so 'minimum: 0' and 'maximum: 0' are only validated if they are " +
+			"exclusive.",
+		"Fields: ExclusiveMaximum are validated against a bound of 0 for the schema: balance. A bound of 0 can not " +
+			"be told apart from a missing bound, so 'minimum: 0' and 'maximum: 0' are only validated if they are " +
+			"exclusive.",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+}
+
+func TestFeatureCheckerFormData(t *testing.T) {
+	input := "testfiles/formData.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	messages := checker.Run()
+	expectedMessageTexts := []string{
+		"Media type: multipart/form-data of request body: uploadPhoto is generated as message with one field per " +
+			"form part (binary parts as bytes). The transcoder has to be configured to decode this media type (e.g.: " +
+			"with a custom marshaler for grpc-gateway).",
+		"Media type: application/x-www-form-urlencoded of request body: login is generated as message with one " +
+			"field per form part (binary parts as bytes). The transcoder has to be configured to decode this media " +
+			"type (e.g.: with a custom marshaler for grpc-gateway).",
+	}
+	validateMessages(t, expectedMessageTexts, messages)
+}
+
+func TestFeatureCheckerServicePerTag(t *testing.T) {
+	input := "testfiles/tags.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	checker := NewGrpcChecker(documentv3)
+	checker.ServicePerTag = true
+	validateMessages(t, []string{}, checker.Run())
+}
+
 func validateMessages(t *testing.T, expectedMessageTexts []string, messages []*plugins.Message) {
 	if len(expectedMessageTexts) != len(messages) {
 		t.Errorf("Number of messages from GrpcChecker does not match expected number")
@@ -94,9 +194,10 @@ func validateMessages(t *testing.T, expectedMessageTexts []string, messages []*p
 	}
 }
 
-func readOpenAPIBinary(input string) *openapiv3.Document {
-	cmd := exec.Command("gnostic", "--pb-out=-", input)
-	b, _ := cmd.Output()
-	documentv3, _ := createOpenAPIDocFromGnosticOutput(b)
+func readOpenAPIDocumentForTest(t *testing.T, input string) *openapiv3.Document {
+	documentv3, err := readOpenAPIDocument(input)
+	if err != nil {
+		t.Fatal(err)
+	}
 	return documentv3
 }
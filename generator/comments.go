@@ -0,0 +1,120 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Field numbers of FileDescriptorProto, DescriptorProto, EnumDescriptorProto and ServiceDescriptorProto. They are used
+// to build the paths of SourceCodeInfo locations (see: google/protobuf/descriptor.proto).
+const (
+	fileMessagesTag         = 4
+	fileEnumsTag            = 5
+	fileServicesTag         = 6
+	messageFieldsTag        = 2
+	messageNestedTag        = 3
+	messageEnumsTag         = 4
+	messageReservedRangeTag = 9
+	messageReservedNameTag  = 10
+	enumValuesTag           = 2
+	serviceMethodsTag       = 2
+)
+
+// Builds the SourceCodeInfo of 'fd', so that the descriptions of the OpenAPI document are printed as leading comments.
+// protoprint orders elements with source locations before elements without, so every element gets a location. The
+// spans are only used to keep the order in which the elements are declared.
+func buildSourceCodeInfo(fd *dpb.FileDescriptorProto, descriptions map[proto.Message]string) {
+	b := &sourceCodeInfoBuilder{info: &dpb.SourceCodeInfo{}, descriptions: descriptions}
+	for i, message := range fd.MessageType {
+		b.addMessage([]int32{fileMessagesTag, int32(i)}, message)
+	}
+	for i, enum := range fd.EnumType {
+		b.addEnum([]int32{fileEnumsTag, int32(i)}, enum)
+	}
+	for i, service := range fd.Service {
+		path := []int32{fileServicesTag, int32(i)}
+		b.add(path, service)
+		for j, method := range service.Method {
+			b.add(append(path, serviceMethodsTag, int32(j)), method)
+		}
+	}
+	fd.SourceCodeInfo = b.info
+}
+
+type sourceCodeInfoBuilder struct {
+	info         *dpb.SourceCodeInfo
+	descriptions map[proto.Message]string
+	line         int32
+}
+
+// Adds the location of the element at 'path'. 'element' is used to look up the description and might be nil.
+func (b *sourceCodeInfoBuilder) add(path []int32, element proto.Message) {
+	location := &dpb.SourceCodeInfo_Location{
+		Path: append([]int32(nil), path...),
+		Span: []int32{b.line, 0, 0},
+	}
+	if description := b.descriptions[element]; element != nil && description != "" {
+		comment := formatComment(description)
+		location.LeadingComments = &comment
+	}
+	b.info.Location = append(b.info.Location, location)
+	b.line++
+}
+
+func (b *sourceCodeInfoBuilder) addMessage(path []int32, message *dpb.DescriptorProto) {
+	b.add(path, message)
+	for i := range message.ReservedRange {
+		b.add(append(path, messageReservedRangeTag, int32(i)), nil)
+	}
+	for i := range message.ReservedName {
+		b.add(append(path, messageReservedNameTag, int32(i)), nil)
+	}
+	for i, field := range message.Field {
+		b.add(append(path, messageFieldsTag, int32(i)), field)
+	}
+	for i, nested := range message.NestedType {
+		b.addMessage(append(path, messageNestedTag, int32(i)), nested)
+	}
+	for i, enum := range message.EnumType {
+		b.addEnum(append(path, messageEnumsTag, int32(i)), enum)
+	}
+}
+
+func (b *sourceCodeInfoBuilder) addEnum(path []int32, enum *dpb.EnumDescriptorProto) {
+	b.add(path, enum)
+	for i, value := range enum.Value {
+		b.add(append(path, enumValuesTag, int32(i)), value)
+	}
+}
+
+// Converts a (possibly multi-line) description into the format protoc uses for comments: every line starts with a
+// space and ends with a newline.
+func formatComment(description string) string {
+	lines := strings.Split(strings.TrimSpace(description), "\n")
+	var b strings.Builder
+	for _, line := range lines {
+		line = strings.TrimRight(line, " \t\r")
+		if line != "" {
+			b.WriteString(" ")
+		}
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
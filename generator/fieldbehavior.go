@@ -0,0 +1,78 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"bytes"
+	"compress/gzip"
+	"io/ioutil"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// Returns the field behaviors (https://github.com/googleapis/googleapis/blob/master/google/api/field_behavior.proto)
+// of the field 'fieldName' of the type 'typeName': required properties, parameters and request bodies are REQUIRED,
+// 'readOnly' properties are OUTPUT_ONLY and 'writeOnly' properties are INPUT_ONLY.
+func (idx *schemaIndex) fieldBehaviors(typeName string, fieldName string) []annotations.FieldBehavior {
+	behaviors := make([]annotations.FieldBehavior, 0)
+	if idx.isRequiredField(typeName, fieldName) {
+		behaviors = append(behaviors, annotations.FieldBehavior_REQUIRED)
+	}
+	schema := idx.fieldSchema(typeName, fieldName)
+	if schema.GetReadOnly() {
+		behaviors = append(behaviors, annotations.FieldBehavior_OUTPUT_ONLY)
+	}
+	if schema.GetWriteOnly() {
+		behaviors = append(behaviors, annotations.FieldBehavior_INPUT_ONLY)
+	}
+	return behaviors
+}
+
+// Sets the option '(google.api.field_behavior)' of 'fd'.
+func setFieldBehaviors(fd *dpb.FieldDescriptorProto, behaviors []annotations.FieldBehavior) error {
+	if len(behaviors) == 0 {
+		return nil
+	}
+	if fd.Options == nil {
+		fd.Options = &dpb.FieldOptions{}
+	}
+	return proto.SetExtension(fd.Options, annotations.E_FieldBehavior, behaviors)
+}
+
+// Returns the FileDescriptorProto of google/api/field_behavior.proto. The generated Go package only registers the
+// compressed descriptor of the file, it does not contain a message we could pass to descriptor.ForMessage.
+func getFieldBehaviorFile() *dpb.FileDescriptorProto {
+	fd, _ := decompressFileDescriptor(proto.FileDescriptor(annotations.E_FieldBehavior.Filename))
+	return fd
+}
+
+// Decompresses a FileDescriptorProto as registered by generated Go packages.
+func decompressFileDescriptor(compressed []byte) (*dpb.FileDescriptorProto, error) {
+	r, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, err
+	}
+	b, err := ioutil.ReadAll(r)
+	if err != nil {
+		return nil, err
+	}
+	fd := &dpb.FileDescriptorProto{}
+	if err := proto.Unmarshal(b, fd); err != nil {
+		return nil, err
+	}
+	return fd, nil
+}
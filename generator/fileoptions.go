@@ -0,0 +1,102 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"gopkg.in/yaml.v2"
+)
+
+// The file options that can be set with plugin parameters and inside of the vendor extension 'x-proto-options' of
+// 'info' (e.g.: 'go_package: github.com/acme/petstore;petstorepb').
+var fileOptionSetters = map[string]func(options *dpb.FileOptions, value string) error{
+	"go_package": func(options *dpb.FileOptions, value string) error {
+		options.GoPackage = proto.String(value)
+		return nil
+	},
+	"java_package": func(options *dpb.FileOptions, value string) error {
+		options.JavaPackage = proto.String(value)
+		return nil
+	},
+	"java_multiple_files": func(options *dpb.FileOptions, value string) error {
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return fmt.Errorf("java_multiple_files: '%s' is not a boolean", value)
+		}
+		options.JavaMultipleFiles = proto.Bool(b)
+		return nil
+	},
+	"objc_class_prefix": func(options *dpb.FileOptions, value string) error {
+		options.ObjcClassPrefix = proto.String(value)
+		return nil
+	},
+	"php_namespace": func(options *dpb.FileOptions, value string) error {
+		options.PhpNamespace = proto.String(value)
+		return nil
+	},
+	"csharp_namespace": func(options *dpb.FileOptions, value string) error {
+		options.CsharpNamespace = proto.String(value)
+		return nil
+	},
+}
+
+// Sets the file option 'name' of 'options' to 'value'. Returns an error if the option is not supported or the value
+// is invalid.
+func setFileOption(options *dpb.FileOptions, name string, value string) error {
+	setter, ok := fileOptionSetters[name]
+	if !ok {
+		return fmt.Errorf("'%s' is not a supported file option", name)
+	}
+	return setter(options, value)
+}
+
+// Returns the options of the file generated for 'document': the options of the vendor extension 'x-proto-options'
+// of 'info', overridden by 'overrides' (the options of the plugin parameters). Returns nil if no option is set.
+func buildFileOptions(document *openapiv3.Document, overrides *dpb.FileOptions) (*dpb.FileOptions, error) {
+	options := &dpb.FileOptions{}
+	for _, extension := range document.GetInfo().GetSpecificationExtension() {
+		if extension.Name != extensionFileOptions {
+			continue
+		}
+		values := make(map[string]interface{})
+		if err := yaml.Unmarshal([]byte(extension.Value.GetYaml()), &values); err != nil {
+			return nil, fmt.Errorf("%s: %s", extensionFileOptions, err)
+		}
+		names := make([]string, 0)
+		for name := range values {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		for _, name := range names {
+			if err := setFileOption(options, name, fmt.Sprint(values[name])); err != nil {
+				return nil, fmt.Errorf("%s: %s", extensionFileOptions, err)
+			}
+		}
+	}
+	if overrides != nil {
+		proto.Merge(options, overrides)
+	}
+
+	if proto.Equal(options, &dpb.FileOptions{}) {
+		return nil, nil
+	}
+	return options, nil
+}
@@ -0,0 +1,103 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"errors"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	plugins "github.com/googleapis/gnostic/plugins"
+)
+
+// Options configures Generate.
+type Options struct {
+	// Package of the generated .proto definitions (e.g.: 'bookstore'). Required.
+	PackageName string
+	// Name of the generated service. Defaults to the capitalized package name.
+	ServiceName string
+	// The file path or URL the document was read from. It is needed to find external OpenAPI descriptions that are
+	// referenced inside of the document (symbolic references). Optional.
+	SourceName string
+	// If set, field numbers are assigned according to the lock and new fields are recorded inside of it.
+	FieldNumberLock *FieldNumberLock
+	// If true, the returned FileDescriptorSet also contains all imported files (e.g.: google/api/annotations.proto),
+	// so that it is self-contained. Otherwise it only contains the generated files.
+	IncludeImports bool
+	// If true, strings with the format 'date-time', 'date' or 'duration' are represented by the well-known types
+	// google.protobuf.Timestamp, google.type.Date and google.protobuf.Duration.
+	TimeTypes bool
+	// Strategy for scalar fields that are nullable or not required: OptionalFieldsNone or OptionalFieldsWrappers.
+	OptionalFields string
+	// If true, the constraints of properties and parameters (e.g.: 'maximum', 'pattern' or 'minItems') are attached
+	// to the fields as protoc-gen-validate rules.
+	ValidateRules bool
+	// If true, the operations are grouped into one service per tag (e.g.: 'PetService' for the tag 'pet'). Operations
+	// with multiple tags belong to the service of their first tag, untagged operations to the default service.
+	ServicePerTag bool
+	// If true, the generated .proto is split into '<package>_types.proto' with the shared messages and enums and one
+	// file per service.
+	SplitFiles bool
+	// File options (e.g.: go_package or java_package) of the generated .proto. They override the options of the vendor
+	// extension 'x-proto-options' of 'info'.
+	FileOptions *dpb.FileOptions
+	// If true, the document is not checked for OpenAPI features that are not supported and no messages are returned.
+	SkipFeatureCheck bool
+}
+
+// Generate converts the OpenAPI description 'document' into .proto definitions without the need of a gnostic plugin
+// environment. The files of the returned FileDescriptorSet are ordered, so that every file comes after its
+// dependencies; the file generated for 'document' is the last one (if SplitFiles is set, the file of the last
+// service). The messages describe OpenAPI features that are not supported by the conversion.
+func Generate(document *openapiv3.Document, opts Options) (*dpb.FileDescriptorSet, []*plugins.Message, error) {
+	if document == nil {
+		return nil, nil, errors.New("no OpenAPI document")
+	}
+	if opts.PackageName == "" {
+		return nil, nil, errors.New("no package name")
+	}
+
+	messages := make([]*plugins.Message, 0)
+	if !opts.SkipFeatureCheck {
+		checker := NewGrpcChecker(document)
+		checker.ValidateRules = opts.ValidateRules
+		checker.ServicePerTag = opts.ServicePerTag
+		checker.OptionalFields = opts.OptionalFields
+		messages = checker.Run()
+	}
+
+	surfaceModel, err := buildSurfaceModel(document, opts.SourceName)
+	if err != nil {
+		return nil, messages, err
+	}
+	renderer := NewRenderer(surfaceModel)
+	renderer.Document = document
+	renderer.Package = opts.PackageName
+	renderer.ServiceName = opts.ServiceName
+	renderer.FieldNumberLock = opts.FieldNumberLock
+	renderer.IncludeImports = opts.IncludeImports
+	renderer.TimeTypes = opts.TimeTypes
+	renderer.OptionalFields = opts.OptionalFields
+	renderer.ValidateRules = opts.ValidateRules
+	renderer.ServicePerTag = opts.ServicePerTag
+	renderer.SplitFiles = opts.SplitFiles
+	renderer.FileOptions = opts.FileOptions
+
+	renderer.FdSet, err = renderer.runFileDescriptorSetGenerator()
+	if err != nil {
+		return nil, messages, err
+	}
+	return renderer.buildDescriptorSet(), messages, nil
+}
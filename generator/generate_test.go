@@ -0,0 +1,80 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"testing"
+
+	prDesc "github.com/jhump/protoreflect/desc"
+)
+
+func TestGenerate(t *testing.T) {
+	input := "testfiles/parameters.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	fdSet, messages, err := Generate(documentv3, Options{
+		PackageName:    "parameters",
+		SourceName:     input,
+		IncludeImports: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Same messages as the feature checker.
+	validateMessages(t, []string{
+		"Fields: Explode are not supported for parameter: param2",
+		"Fields: Default are not supported for the schema: Items of param2",
+		"Fields: Default are not supported for the schema: param4",
+	}, messages)
+
+	// The set is self-contained and contains the same definitions the plugin renders.
+	f, err := NewRenderer(nil).RenderProto(fdSet, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, string(f.Data), "goldstandard/parameters.proto")
+}
+
+func TestGenerateServiceName(t *testing.T) {
+	input := "testfiles/parameters.yaml"
+	documentv3 := readOpenAPIDocumentForTest(t, input)
+
+	fdSet, _, err := Generate(documentv3, Options{
+		PackageName:      "parameters",
+		ServiceName:      "ParameterService",
+		IncludeImports:   true,
+		SkipFeatureCheck: true,
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	fd, err := prDesc.CreateFileDescriptorFromSet(fdSet)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fd.FindService("parameters.ParameterService") == nil {
+		t.Errorf("Service parameters.ParameterService not found")
+	}
+}
+
+func TestGenerateWithoutPackageName(t *testing.T) {
+	documentv3 := readOpenAPIDocumentForTest(t, "testfiles/parameters.yaml")
+
+	_, _, err := Generate(documentv3, Options{})
+	if err == nil {
+		t.Errorf("Expected an error")
+	}
+}
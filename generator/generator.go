@@ -15,31 +15,61 @@
 package generator
 
 import (
+	"fmt"
 	"github.com/golang/protobuf/descriptor"
 	"github.com/golang/protobuf/proto"
 	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	"github.com/golang/protobuf/ptypes/empty"
+	"github.com/googleapis/gnostic-grpc/metadata"
 	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	"github.com/googleapis/gnostic/compiler"
 	surface_v1 "github.com/googleapis/gnostic/surface"
 	"google.golang.org/genproto/googleapis/api/annotations"
 	"log"
 	nethttp "net/http"
-	"os/exec"
 	"path"
 	"path/filepath"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
 var protoBufScalarTypes = getProtobufTypes()
 var openAPITypesToProtoBuf = getOpenAPITypesToProtoBufTypes()
 var openAPIScalarTypes = getOpenAPIScalarTypes()
 
-// Gathers all symbolic references we generated in recursive calls.
-var generatedSymbolicReferences = make(map[string]bool, 0)
+// Matches the variables of a path template (e.g.: '{petId}').
+var pathVariablePattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// Matches valid .proto identifiers (e.g.: field names set with 'x-proto-name').
+var identifierPattern = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+// The gnostic compiler keeps its file and info caches inside of package variables, which the surface model relies on
+// to find symbolic references. Access to them is serialized, so that renderers can run in parallel.
+var compilerMutex sync.Mutex
+
+// generationContext holds the state of a single run of the generator. It is shared by the renderers of the symbolic
+// references, so that every external description is generated only once.
+type generationContext struct {
+	// Gathers all symbolic references we generated in recursive calls.
+	symbolicReferences map[string]bool
+	// Gathers all messages and enums that have been generated from symbolic references in recursive calls.
+	messages map[string]string
+	// The fully qualified names of the enums inside of 'messages'.
+	enums map[string]bool
+	// The names of the generated fields by the name of their surface model type and field.
+	fieldNames map[string]map[string]string
+}
 
-// Gathers all messages that have been generated from symbolic references in recursive calls.
-var generatedMessages = make(map[string]string, 0)
+func newGenerationContext() *generationContext {
+	return &generationContext{
+		symbolicReferences: make(map[string]bool),
+		messages:           make(map[string]string),
+		enums:              make(map[string]bool),
+		fieldNames:         make(map[string]map[string]string),
+	}
+}
 
 // Uses the output of gnostic to return a dpb.FileDescriptorSet (in bytes). 'renderer' contains
 // the 'model' (surface model) which has all the relevant data to create the dpb.FileDescriptorSet.
@@ -51,14 +81,41 @@ var generatedMessages = make(map[string]string, 0)
 //		3. buildMessagesFromTypes is called to create all messages which will be rendered in .proto
 //		4. buildServiceFromMethods is called to create a RPC service which will be rendered in .proto
 func (renderer *Renderer) runFileDescriptorSetGenerator() (fdSet *dpb.FileDescriptorSet, err error) {
+	renderer.generation = newGenerationContext()
+	renderer.splitFiles = nil
+	return renderer.buildFileDescriptorSet()
+}
+
+// Does the actual work of runFileDescriptorSetGenerator inside of the generation context of 'renderer'.
+func (renderer *Renderer) buildFileDescriptorSet() (fdSet *dpb.FileDescriptorSet, err error) {
+	if err := validateOptionalFieldsStrategy(renderer.OptionalFields); err != nil {
+		return nil, err
+	}
+
 	syntax := "proto3"
 	n := renderer.Package + ".proto"
+	document, model, err := renderer.nameOperations()
+	if err != nil {
+		return nil, err
+	}
+	renderer.model = model
+	renderer.schemas = newSchemaIndex(document, renderer.model)
+	renderer.pinnedFields = make(map[*dpb.FieldDescriptorProto]bool)
+	renderer.metadataParameters = make(map[string][]*metadata.MetadataParameter)
+	renderer.SymbolicFdSets = make([]*dpb.FileDescriptorSet, 0)
+	renderer.descriptions = make(map[proto.Message]string)
+
+	options, err := buildFileOptions(renderer.Document, renderer.FileOptions)
+	if err != nil {
+		return nil, err
+	}
 
 	// mainProto is the proto we ultimately want to render.
 	mainProto := &dpb.FileDescriptorProto{
 		Name:    &n,
 		Package: &renderer.Package,
 		Syntax:  &syntax,
+		Options: options,
 	}
 	fdSet = &dpb.FileDescriptorSet{
 		File: []*dpb.FileDescriptorProto{mainProto},
@@ -70,28 +127,66 @@ func (renderer *Renderer) runFileDescriptorSetGenerator() (fdSet *dpb.FileDescri
 		return nil, err
 	}
 
-	addDependencies(fdSet)
-
 	err = buildMessagesFromTypes(mainProto, renderer)
 	if err != nil {
 		return nil, err
 	}
 
+	if renderer.FieldNumberLock != nil {
+		err = renderer.FieldNumberLock.apply(mainProto, renderer.pinnedFields)
+		if err != nil {
+			return nil, err
+		}
+	}
+
 	err = buildServiceFromMethods(mainProto, renderer)
 	if err != nil {
 		return nil, err
 	}
 
+	addDependencies(fdSet)
+	buildWellKnownDependencies(fdSet, mainProto)
+	buildSourceCodeInfo(mainProto, renderer.descriptions)
+
 	return fdSet, err
 }
 
+// Returns the OpenAPI description and the surface model the file is generated from. If operations of Document have no
+// operationId or share one with other operations, they are named inside of a copy of Document (see: getOperationIds)
+// and the surface model is built from the copy. Otherwise the messages of an operation would not be named like its
+// RPC or would collide with the messages of other operations. Document and Model are never changed.
+func (renderer *Renderer) nameOperations() (*openapiv3.Document, *surface_v1.Model, error) {
+	if renderer.Document == nil {
+		return nil, renderer.Model, nil
+	}
+	named := true
+	for operation, operationId := range getOperationIds(renderer.Document) {
+		named = named && operation.OperationId == operationId
+	}
+	if named {
+		return renderer.Document, renderer.Model, nil
+	}
+
+	document := proto.Clone(renderer.Document).(*openapiv3.Document)
+	for operation, operationId := range getOperationIds(document) {
+		operation.OperationId = operationId
+	}
+	model, err := buildSurfaceModel(document, "")
+	if err != nil {
+		return nil, nil, err
+	}
+	// The location of Document is unknown, so the symbolic references are taken from Model.
+	model.SymbolicReferences = renderer.Model.SymbolicReferences
+	return document, model, nil
+}
+
 // Adds the dependencies to the FileDescriptor we want to render. This essentially makes the 'import' statements
 // inside the .proto definition.
 func addDependencies(fdSet *dpb.FileDescriptorSet) {
 	// At last, we need to add the dependencies to the FileDescriptorProto in order to get them rendered.
 	lastFdProto := getLast(fdSet.File)
 	for _, fd := range fdSet.File {
-		if fd != lastFdProto {
+		if fd != lastFdProto && !isWellKnownTypeFile(fd.GetName()) {
 			lastFdProto.Dependency = append(lastFdProto.Dependency, *fd.Name)
 		}
 	}
@@ -100,43 +195,48 @@ func addDependencies(fdSet *dpb.FileDescriptorSet) {
 // buildSymbolicReferences recursively generates all .proto definitions to external OpenAPI descriptions (URLs to other
 // descriptions inside the current description).
 func buildSymbolicReferences(fdSet *dpb.FileDescriptorSet, renderer *Renderer) (err error) {
-	symbolicReferences := renderer.Model.SymbolicReferences
+	symbolicReferences := renderer.model.SymbolicReferences
 	symbolicReferences = trimAndRemoveDuplicates(symbolicReferences)
 
 	symbolicFileDescriptorProtos := make([]*dpb.FileDescriptorProto, 0)
 	for _, ref := range symbolicReferences {
-		if _, alreadyGenerated := generatedSymbolicReferences[ref]; !alreadyGenerated {
-			generatedSymbolicReferences[ref] = true
+		if _, alreadyGenerated := renderer.generation.symbolicReferences[ref]; !alreadyGenerated {
+			renderer.generation.symbolicReferences[ref] = true
 
-			// Lets get the standard gnostic output from the symbolic reference.
-			cmd := exec.Command("gnostic", "--pb-out=-", ref)
-			b, err := cmd.Output()
-			if err != nil {
-				return err
-			}
-
-			// Construct an OpenAPI document v3.
-			document, err := createOpenAPIDocFromGnosticOutput(b)
+			document, err := readOpenAPIDocument(ref)
 			if err != nil {
 				return err
 			}
 
 			// Create the surface model. Keep in mind that this resolves the references of the symbolic reference again!
-			surfaceModel, err := surface_v1.NewModelFromOpenAPI3(document, ref)
+			surfaceModel, err := buildSurfaceModel(document, ref)
 			if err != nil {
 				return err
 			}
 
 			// Recursively call the generator.
 			recursiveRenderer := NewRenderer(surfaceModel)
+			recursiveRenderer.Document = document
+			recursiveRenderer.FieldNumberLock = renderer.FieldNumberLock
+			recursiveRenderer.generation = renderer.generation
+			recursiveRenderer.TimeTypes = renderer.TimeTypes
+			recursiveRenderer.OptionalFields = renderer.OptionalFields
+			recursiveRenderer.ValidateRules = renderer.ValidateRules
+			recursiveRenderer.ServicePerTag = renderer.ServicePerTag
 			fileName := path.Base(ref)
 			recursiveRenderer.Package = strings.TrimSuffix(fileName, filepath.Ext(fileName))
-			newFdSet, err := recursiveRenderer.runFileDescriptorSetGenerator()
+			newFdSet, err := recursiveRenderer.buildFileDescriptorSet()
 			if err != nil {
 				return err
 			}
 			renderer.SymbolicFdSets = append(renderer.SymbolicFdSets, newFdSet)
 
+			// The files of well-known types that are used inside of the symbolic reference are needed as well.
+			for _, fd := range newFdSet.File {
+				if isWellKnownTypeFile(fd.GetName()) && !containsFile(symbolicFileDescriptorProtos, fd.GetName()) {
+					symbolicFileDescriptorProtos = append(symbolicFileDescriptorProtos, fd)
+				}
+			}
 			symbolicProto := getLast(newFdSet.File)
 			symbolicFileDescriptorProtos = append(symbolicFileDescriptorProtos, symbolicProto)
 		}
@@ -196,13 +296,69 @@ func buildDependencies(fdSet *dpb.FileDescriptorSet) {
 // Builds protobuf messages from the surface model types. If the type is a RPC request parameter
 // the fields have to follow certain rules, and therefore have to be validated.
 func buildMessagesFromTypes(descr *dpb.FileDescriptorProto, renderer *Renderer) (err error) {
-	types := renderer.Model.Types
+	types := renderer.model.Types
+	inlineAllOfTypes := getInlineAllOfTypes(types)
+
+	// The types of an operation are named after the operation, so operations with the same name cannot be told apart.
+	// Operations are only renamed if Document is set (see: nameOperations).
+	operations := make(map[string]*surface_v1.Method)
+	for _, method := range renderer.model.Methods {
+		if other, ok := operations[method.Name]; ok {
+			return fmt.Errorf("the operations '%s %s' and '%s %s' have the same name: %s", other.Method, other.Path, method.Method, method.Path, method.Name)
+		}
+		operations[method.Name] = method
+	}
 
 	for _, t := range types {
+		if inlineAllOfTypes[t.Name] {
+			continue // The fields of this type are merged into the type that declares the 'allOf'.
+		}
+		if _, ok := renderer.schemas.replacedType(t.Name); ok {
+			continue // Fields and methods use a well-known type instead.
+		}
+		if _, ok := renderer.schemas.formBodyType(t.Name); ok {
+			continue // Fields use the message of the form instead.
+		}
+		if renderer.schemas.isEnumType(t.Name) {
+			// Named enum schemas are rendered as top-level enums instead of messages.
+			enum, err := buildEnumDescriptorProto(renderer.schemas.protoTypeName(t.Name), renderer.schemas.components[t.Name])
+			if err != nil {
+				return err
+			}
+			renderer.descriptions[enum] = renderer.schemas.typeDescription(t.Name)
+			descr.EnumType = append(descr.EnumType, enum)
+			// Fields of other descriptions refer to the enum as well (see: buildSymbolicReferences).
+			renderer.generation.messages[*enum.Name] = renderer.Package + "." + *enum.Name
+			renderer.generation.enums[renderer.Package+"."+*enum.Name] = true
+			continue
+		}
+
 		message := &dpb.DescriptorProto{}
-		setMessageDescriptorName(message, t.Name)
+		setMessageDescriptorName(message, renderer.schemas.protoTypeName(t.Name))
+		renderer.descriptions[message] = renderer.schemas.typeDescription(t.Name)
 
-		for i, f := range t.Fields {
+		fields, err := mergeAllOfFields(t, types, make(map[string]bool))
+		if err != nil {
+			return err
+		}
+		optional := make(map[*dpb.FieldDescriptorProto]bool)
+		surfaceNames := make(map[*dpb.FieldDescriptorProto]string)
+		explicitNames := make(map[*dpb.FieldDescriptorProto]bool)
+
+		for i, declaredField := range fields {
+			f := declaredField.Field
+			if location := renderer.schemas.parameterLocation(declaredField.owner, f.Name); isMetadataParameter(location) {
+				// Header and cookie parameters are sent as gRPC metadata.
+				required := renderer.schemas.isRequiredField(declaredField.owner, f.Name)
+				parameter := buildMetadataParameter(f.Name, location, required)
+				renderer.metadataParameters[t.Name] = append(renderer.metadataParameters[t.Name], parameter)
+				continue
+			}
+			if formTypeName, ok := renderer.schemas.formBodyType(f.Type); ok {
+				// The request body refers to the message of the form directly.
+				f = proto.Clone(f).(*surface_v1.Field)
+				f.Type = formTypeName
+			}
 			if isRequestParameter(t) {
 				if f.Position == surface_v1.Position_PATH {
 					validatePathParameter(f)
@@ -217,22 +373,91 @@ func buildMessagesFromTypes(descr *dpb.FileDescriptorProto, renderer *Renderer)
 			setFieldDescriptorLabel(fieldDescriptor, f)
 			setFieldDescriptorName(fieldDescriptor, f)
 			setFieldDescriptorType(fieldDescriptor, f)
-			setFieldDescriptorTypeName(fieldDescriptor, f, renderer.Package)
+			setFieldDescriptorTypeName(fieldDescriptor, f, renderer.Package, renderer.schemas, renderer.generation.messages)
+			if typeName, ok := getTimeFormatType(f); ok && renderer.TimeTypes {
+				setFieldDescriptorMessageType(fieldDescriptor, typeName)
+			}
+			if typeName, ok := renderer.schemas.replacedType(f.Type); ok {
+				setFieldDescriptorWellKnownType(fieldDescriptor, typeName)
+			}
+
+			// Inline enums are represented as nested types inside of the descriptor.
+			if enumSchema := renderer.schemas.enumForField(declaredField.owner, f.Name); enumSchema != nil {
+				enum, err := buildEnumDescriptorProto(cleanTypeName(f.Name), enumSchema)
+				if err != nil {
+					return err
+				}
+				message.EnumType = append(message.EnumType, enum)
+				typeName := renderer.Package + "." + *message.Name + "." + *enum.Name
+				setFieldDescriptorEnumType(fieldDescriptor, typeName)
+			} else if renderer.schemas.isEnumType(f.Type) || renderer.generation.enums[fieldDescriptor.GetTypeName()] {
+				setFieldDescriptorEnumType(fieldDescriptor, *fieldDescriptor.TypeName)
+			}
 
 			// Maps are represented as nested types inside of the descriptor.
 			if f.Kind == surface_v1.FieldKind_MAP {
-				if strings.Contains(f.Type, "map[string][]") {
-					// Not supported for now: https://github.com/LorenzHW/gnostic-grpc/issues/3#issuecomment-509348357
-					continue
-				}
 				mapDescriptorProto := buildMapDescriptorProto(f)
+				valueField, valueType := mapDescriptorProto.Field[1], f.Type[11:]
+				if strings.HasPrefix(valueType, "[]") {
+					// The values of maps cannot be repeated, so every list is wrapped inside of a message. The field of the
+					// map is always called 'additional_properties', so the message is named after the property or schema
+					// that owns the map instead (e.g.: 'TagsValues' for the property 'tags').
+					valuesDescriptorProto := buildMapValuesDescriptorProto(*message.Name+"Values", valueType[2:])
+					renderer.setMapValueTypeName(valuesDescriptorProto.Field[0], valueType[2:])
+					setFieldDescriptorMessageType(valueField, *valuesDescriptorProto.Name)
+					message.NestedType = append(message.NestedType, valuesDescriptorProto)
+				} else {
+					renderer.setMapValueTypeName(valueField, valueType)
+				}
 				fieldDescriptor.TypeName = mapDescriptorProto.Name
 				message.NestedType = append(message.NestedType, mapDescriptorProto)
 			}
+
+			// Vendor extensions have the last word.
+			fieldSchema := renderer.schemas.fieldSchema(declaredField.owner, f.Name)
+			pinned, err := applyFieldExtensions(fieldDescriptor, fieldSchema)
+			if err != nil {
+				return fmt.Errorf("property '%s' of %s: %v", f.Name, t.Name, err)
+			}
+			if _, ok := getExtension(fieldSchema.GetSpecificationExtension(), extensionName); ok {
+				explicitNames[fieldDescriptor] = true
+			}
+			if pinned {
+				renderer.pinnedFields[fieldDescriptor] = true
+			}
+			if err := setFieldBehaviors(fieldDescriptor, renderer.schemas.fieldBehaviors(declaredField.owner, f.Name)); err != nil {
+				return err
+			}
+			if renderer.ValidateRules {
+				rules := buildValidateRules(fieldDescriptor, renderer.schemas.fieldSchema(declaredField.owner, f.Name))
+				if err := setValidateRules(fieldDescriptor, rules); err != nil {
+					return err
+				}
+			}
+			renderer.descriptions[fieldDescriptor] = renderer.schemas.fieldDescription(declaredField.owner, f.Name)
+			optional[fieldDescriptor] = renderer.schemas.isOptionalField(declaredField.owner, f.Name)
+			surfaceNames[fieldDescriptor] = f.Name
 			message.Field = append(message.Field, fieldDescriptor)
 		}
+		makeFieldNamesUnique(message, surfaceNames, explicitNames)
+		fieldNames := make(map[string]string)
+		for _, fd := range message.Field {
+			if other, ok := fieldNames[fd.GetName()]; ok {
+				return fmt.Errorf("the properties '%s' and '%s' of %s have the same field name: %s", other, surfaceNames[fd], t.Name, fd.GetName())
+			}
+			fieldNames[fd.GetName()] = surfaceNames[fd]
+			if renderer.generation.fieldNames[t.Name] == nil {
+				renderer.generation.fieldNames[t.Name] = make(map[string]string)
+			}
+			renderer.generation.fieldNames[t.Name][surfaceNames[fd]] = fd.GetName()
+		}
+		if err := assignFieldNumbers(message, renderer.pinnedFields); err != nil {
+			return err
+		}
+		buildOneofDecls(message, t, renderer.schemas, surfaceNames)
+		buildOptionalFields(message, optional, renderer.OptionalFields)
 		descr.MessageType = append(descr.MessageType, message)
-		generatedMessages[*message.Name] = renderer.Package + "." + *message.Name
+		renderer.generation.messages[*message.Name] = renderer.Package + "." + *message.Name
 	}
 	return nil
 }
@@ -240,46 +465,311 @@ func buildMessagesFromTypes(descr *dpb.FileDescriptorProto, renderer *Renderer)
 // Builds a protobuf RPC service. For every method the corresponding gRPC-HTTP transcoding options (https://github.com/googleapis/googleapis/blob/master/google/api/http.proto)
 // have to be set.
 func buildServiceFromMethods(descr *dpb.FileDescriptorProto, renderer *Renderer) (err error) {
-	methods := renderer.Model.Methods
-	serviceName := strings.Title(renderer.Package)
+	methods := renderer.model.Methods
+	serviceName := renderer.ServiceName
+	if serviceName == "" {
+		serviceName = strings.Title(renderer.Package)
+	}
 
-	service := &dpb.ServiceDescriptorProto{
-		Name: &serviceName,
+	// With ServicePerTag every tag gets its own service, untagged operations are part of the default service (the
+	// service of the tag "").
+	descr.Service = make([]*dpb.ServiceDescriptorProto, 0)
+	namer := newRPCNamer(descr)
+	services := make(map[string]*dpb.ServiceDescriptorProto)
+	getService := func(tag string) *dpb.ServiceDescriptorProto {
+		if service, ok := services[tag]; ok {
+			return service
+		}
+		name := serviceName
+		if tag != "" {
+			name = getServiceNameForTag(tag)
+		}
+		service := &dpb.ServiceDescriptorProto{Name: proto.String(namer.serviceName(name))}
+		services[tag] = service
+		descr.Service = append(descr.Service, service)
+		return service
+	}
+	if !renderer.ServicePerTag {
+		getService("")
 	}
-	descr.Service = []*dpb.ServiceDescriptorProto{service}
 
 	for _, method := range methods {
+		var service *dpb.ServiceDescriptorProto
+		if tag, ok := renderer.schemas.operationTag(method.Name); ok && renderer.ServicePerTag {
+			service = getService(tag)
+			renderer.descriptions[service] = renderer.schemas.tagDescription(tag)
+		} else {
+			service = getService("")
+		}
+
+		metadataParameters := renderer.metadataParameters[method.ParametersTypeName]
 		mOptionsDescr := &dpb.MethodOptions{}
-		requestBody := getRequestBodyForRequestParameters(method.ParametersTypeName, renderer.Model.Types)
-		httpRule := getHttpRuleForMethod(method, requestBody)
+		requestBody := getRequestBodyForRequestParameters(method.ParametersTypeName, renderer.model.Types, renderer.schemas)
+		inputType, replacedInput := renderer.schemas.replacedType(method.ParametersTypeName)
+		if replacedInput {
+			// The whole request is the (binary) body.
+			all := "*"
+			requestBody = &all
+		}
+		httpRule := getHttpRuleForMethod(method, requestBody, renderer.generation.fieldNames[method.ParametersTypeName])
+		outputType, replacedOutput := renderer.schemas.replacedType(method.ResponsesTypeName)
+		if !replacedOutput {
+			httpRule.ResponseBody = getResponseBodyForResponses(method.ResponsesTypeName, renderer.model.Types, renderer.schemas)
+		}
 		if err := proto.SetExtension(mOptionsDescr, annotations.E_Http, &httpRule); err != nil {
 			return err
 		}
 
-		method.ParametersTypeName = cleanTypeName(method.ParametersTypeName)
-		method.ResponsesTypeName = cleanTypeName(method.ResponsesTypeName)
-		method.ParametersTypeName = strings.Title(method.ParametersTypeName)
-		method.ResponsesTypeName = strings.Title(method.ResponsesTypeName)
-
-		if method.ParametersTypeName == "" {
-			method.ParametersTypeName = "google.protobuf.Empty"
+		// The surface model is not changed, so that the generator can run again on the same renderer.
+		if !replacedInput {
+			inputType = getMethodTypeName(method.ParametersTypeName, renderer.schemas)
 		}
-		if method.ResponsesTypeName == "" {
-			method.ResponsesTypeName = "google.protobuf.Empty"
+		if !replacedOutput {
+			outputType = getMethodTypeName(method.ResponsesTypeName, renderer.schemas)
 		}
 
 		mDescr := &dpb.MethodDescriptorProto{
-			Name:       &method.Name,
-			InputType:  &method.ParametersTypeName,
-			OutputType: &method.ResponsesTypeName,
+			Name:       proto.String(namer.name(method)),
+			InputType:  proto.String(inputType),
+			OutputType: proto.String(outputType),
 			Options:    mOptionsDescr,
 		}
 
+		if err := setMethodMetadata(mDescr, metadataParameters); err != nil {
+			return err
+		}
+
+		renderer.descriptions[mDescr] = renderer.schemas.methodDescription(method.Name)
 		service.Method = append(service.Method, mDescr)
 	}
 	return nil
 }
 
+// Returns the name of the message that is generated for the surface model type 'typeName' of the parameters or
+// responses of a method or google.protobuf.Empty if the method has none.
+func getMethodTypeName(typeName string, schemas *schemaIndex) string {
+	if typeName == "" {
+		return "google.protobuf.Empty"
+	}
+	return schemas.protoTypeName(typeName)
+}
+
+// A field together with the name of the surface model type that declares it. Because of 'allOf' this is not
+// necessarily the type the field is rendered in.
+type declaredField struct {
+	*surface_v1.Field
+	owner string
+}
+
+// 'allOf' is represented by the surface model as fields ('all_of_1', 'all_of_2', ...) that reference the types of the
+// subschemas. Those fields are replaced by the (recursively merged) fields of the referenced types, so that a single
+// message with all properties is generated. The order of the fields is the order of the properties and subschemas
+// inside of the OpenAPI description, so the field numbers are deterministic. Returns an error if two subschemas
+// declare the same property with different types. 'visited' is used to detect cycles.
+func mergeAllOfFields(t *surface_v1.Type, types []*surface_v1.Type, visited map[string]bool) ([]*declaredField, error) {
+	visited[t.Name] = true
+	defer delete(visited, t.Name)
+
+	fields := make([]*declaredField, 0)
+	fieldsByName := make(map[string]*declaredField)
+	for _, f := range t.Fields {
+		candidates := []*declaredField{{Field: f, owner: t.Name}}
+		if isAllOfField(f) {
+			if subType := findType(types, f.Type); subType != nil {
+				if visited[subType.Name] {
+					return nil, fmt.Errorf("cyclic allOf: %s references %s", t.Name, subType.Name)
+				}
+				subFields, err := mergeAllOfFields(subType, types, visited)
+				if err != nil {
+					return nil, err
+				}
+				candidates = subFields
+			}
+		}
+
+		for _, candidate := range candidates {
+			if existing, ok := fieldsByName[candidate.Name]; ok {
+				if existing.Type != candidate.Type || existing.Kind != candidate.Kind || existing.Format != candidate.Format {
+					return nil, fmt.Errorf("conflicting types for property '%s' inside of allOf of %s: '%s' (%s) and '%s' (%s)",
+						candidate.Name, t.Name, describeField(existing.Field), existing.owner, describeField(candidate.Field), candidate.owner)
+				}
+				continue // The same property declared twice.
+			}
+			fieldsByName[candidate.Name] = candidate
+			fields = append(fields, candidate)
+		}
+	}
+	return fields, nil
+}
+
+// Returns the names of the types the surface model created for inline subschemas of 'allOf'. Those types are not
+// rendered, because their fields are merged into the type that declares the 'allOf'.
+func getInlineAllOfTypes(types []*surface_v1.Type) map[string]bool {
+	inlineTypes := make(map[string]bool)
+	for _, t := range types {
+		for _, f := range t.Fields {
+			if isAllOfField(f) && f.Type == t.Name+"AllOf"+strings.TrimPrefix(f.Name, "all_of_") {
+				inlineTypes[f.Type] = true
+			}
+		}
+	}
+	return inlineTypes
+}
+
+// Returns true if 'f' is the field the surface model created for a subschema of 'allOf'.
+func isAllOfField(f *surface_v1.Field) bool {
+	return strings.HasPrefix(f.Name, "all_of_") && f.Kind == surface_v1.FieldKind_REFERENCE
+}
+
+// Returns a short description of the type of 'f' for error messages.
+func describeField(f *surface_v1.Field) string {
+	description := strings.ToLower(f.Kind.String()) + " " + f.Type
+	if f.Format != "" {
+		description += " (" + f.Format + ")"
+	}
+	return description
+}
+
+// Polymorphic schemas ('oneOf' and 'anyOf') are represented as oneof inside of the descriptor. The surface model
+// creates a field for every variant ('one_of_1', 'one_of_2', ...), those fields are grouped together into a oneof and
+// renamed according to the discriminator of the schema. 'surfaceNames' are the names of the surface model fields.
+func buildOneofDecls(message *dpb.DescriptorProto, t *surface_v1.Type, schemas *schemaIndex, surfaceNames map[*dpb.FieldDescriptorProto]string) {
+	schema := schemas.types[t.Name]
+	discriminator := schema.GetDiscriminator()
+
+	for _, group := range []string{"one_of", "any_of"} {
+		var oneofIndex *int32
+		for _, fd := range message.Field {
+			if g, ok := schemas.subSchemaGroup(t.Name, surfaceNames[fd]); !ok || g != group {
+				continue // Properties are never part of a oneof, even if their name starts with 'one_of_'.
+			}
+			if *fd.Label == dpb.FieldDescriptorProto_LABEL_REPEATED {
+				continue // Repeated fields and maps are not allowed inside of a oneof.
+			}
+			if oneofIndex == nil {
+				oneofName := group
+				if discriminator != nil && (group == "one_of" || len(schema.OneOf) == 0) {
+					oneofName = toSnakeCase(discriminator.PropertyName)
+					if hasFieldWithName(message, oneofName) {
+						oneofName += "_oneof"
+					}
+				}
+				message.OneofDecl = append(message.OneofDecl, &dpb.OneofDescriptorProto{Name: &oneofName})
+				index := int32(len(message.OneofDecl) - 1)
+				oneofIndex = &index
+			}
+			fd.OneofIndex = oneofIndex
+
+			// Inline variants keep the name of the surface model. References are named after the variant.
+			if f := t.FieldWithName(surfaceNames[fd]); f != nil && schemas.fieldSchema(t.Name, f.Name) == nil {
+				name := toSnakeCase(getDiscriminatorValue(discriminator, f.Type))
+				if !hasFieldWithName(message, name) {
+					fd.Name = &name
+				}
+			}
+		}
+	}
+}
+
+// Applies the vendor extensions 'x-proto-name', 'x-proto-type' and 'x-proto-field-number' of the property 'schema'
+// to 'fd'. Returns true if the field number was pinned with 'x-proto-field-number'.
+func applyFieldExtensions(fd *dpb.FieldDescriptorProto, schema *openapiv3.Schema) (pinned bool, err error) {
+	extensions := schema.GetSpecificationExtension()
+
+	if name, ok := getExtension(extensions, extensionName); ok {
+		if !identifierPattern.MatchString(name) {
+			return false, fmt.Errorf("%s: '%s' is not a valid field name", extensionName, name)
+		}
+		fd.Name = &name
+	}
+
+	if typeName, ok := getExtension(extensions, extensionType); ok {
+		if fd.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE {
+			return false, fmt.Errorf("%s: the type of message and map fields cannot be changed", extensionType)
+		}
+		protoType, ok := protoBufScalarTypes[typeName]
+		if !ok {
+			return false, fmt.Errorf("%s: '%s' is not a protobuf scalar type", extensionType, typeName)
+		}
+		fd.Type = &protoType
+		fd.TypeName = nil
+	}
+
+	if value, ok := getExtension(extensions, extensionFieldNumber); ok {
+		n, err := strconv.ParseInt(value, 10, 32)
+		if err != nil || !isValidFieldNumber(int32(n)) {
+			return false, fmt.Errorf("%s: '%s' is not a valid field number", extensionFieldNumber, value)
+		}
+		number := int32(n)
+		fd.Number = &number
+		return true, nil
+	}
+	return false, nil
+}
+
+// Snake casing can map several properties to the same field name (e.g.: 'photoUrls' and 'photo_urls'). Names that
+// are set with 'x-proto-name' and names that equal the name of their property ('surfaceNames') are kept, all other
+// names get a number (starting with 2) in the order of the fields. The JSON names stay the same.
+func makeFieldNamesUnique(message *dpb.DescriptorProto, surfaceNames map[*dpb.FieldDescriptorProto]string, explicitNames map[*dpb.FieldDescriptorProto]bool) {
+	kept := func(fd *dpb.FieldDescriptorProto) bool {
+		return explicitNames[fd] || surfaceNames[fd] == fd.GetName()
+	}
+	used := make(map[string]bool)
+	for _, fd := range message.Field {
+		if kept(fd) {
+			used[fd.GetName()] = true
+		}
+	}
+	for _, fd := range message.Field {
+		if kept(fd) {
+			continue
+		}
+		name := fd.GetName()
+		for i := 2; used[name]; i++ {
+			name = fd.GetName() + strconv.Itoa(i)
+		}
+		used[name] = true
+		fd.Name = &name
+	}
+}
+
+// Numbers the fields of 'message' in order. Fields with a pinned number keep their number, all other fields get the
+// next number that is not used by a pinned field.
+func assignFieldNumbers(message *dpb.DescriptorProto, pinned map[*dpb.FieldDescriptorProto]bool) error {
+	used := make(map[int32]string)
+	for _, fd := range message.Field {
+		if pinned[fd] {
+			if other, ok := used[fd.GetNumber()]; ok {
+				return fmt.Errorf("fields '%s' and '%s' of %s have the same field number: %d", other, fd.GetName(), message.GetName(), fd.GetNumber())
+			}
+			used[fd.GetNumber()] = fd.GetName()
+		}
+	}
+
+	var next int32 = 1
+	for _, fd := range message.Field {
+		if pinned[fd] {
+			continue
+		}
+		for used[next] != "" || !isValidFieldNumber(next) {
+			next++
+		}
+		n := next
+		fd.Number = &n
+		next++
+	}
+	return nil
+}
+
+// The highest field number that protocol buffers allow.
+const maxFieldNumber = 536870911
+
+// Field numbers 19000 through 19999 are reserved for the protocol buffer implementation.
+// See: https://developers.google.com/protocol-buffers/docs/proto3#assigning-field-numbers
+func isValidFieldNumber(n int32) bool {
+	return n >= 1 && n <= maxFieldNumber && (n < 19000 || n > 19999)
+}
+
 // Builds the necessary descriptor to render a map. (https://developers.google.com/protocol-buffers/docs/proto3#maps)
 // A map is represented as nested message with two fields: 'key', 'value' and the Options set accordingly.
 func buildMapDescriptorProto(field *surface_v1.Field) *dpb.DescriptorProto {
@@ -294,6 +784,38 @@ func buildMapDescriptorProto(field *surface_v1.Field) *dpb.DescriptorProto {
 	return mapDP
 }
 
+// Builds the message 'name' that wraps the list of values of a 'map[string][]valueType' (e.g.: 'message TagsValues {
+// repeated string values = 1; }').
+func buildMapValuesDescriptorProto(name string, valueType string) *dpb.DescriptorProto {
+	v := "values"
+	var number int32 = 1
+	l := dpb.FieldDescriptorProto_LABEL_REPEATED
+	valuesField := &dpb.FieldDescriptorProto{
+		Name:     &v,
+		Number:   &number,
+		Label:    &l,
+		Type:     getProtoTypeForMapValueType(valueType),
+		TypeName: getTypeNameForMapValueType(valueType),
+	}
+	return &dpb.DescriptorProto{Name: &name, Field: []*dpb.FieldDescriptorProto{valuesField}}
+}
+
+// Sets the name of the message or enum 'fd' refers to, if the value type 'valueType' of a map is not a scalar.
+func (renderer *Renderer) setMapValueTypeName(fd *dpb.FieldDescriptorProto, valueType string) {
+	if fd.TypeName == nil {
+		return
+	}
+	if typeName, ok := renderer.schemas.replacedType(valueType); ok {
+		fd.TypeName = &typeName
+		return
+	}
+	typeName := renderer.schemas.protoTypeName(valueType)
+	fd.TypeName = &typeName
+	if renderer.schemas.isEnumType(valueType) {
+		setFieldDescriptorEnumType(fd, typeName)
+	}
+}
+
 // Builds the necessary 'key', 'value' fields for the map descriptor.
 func buildKeyValueFields(field *surface_v1.Field) []*dpb.FieldDescriptorProto {
 	k, v := "key", "value"
@@ -318,6 +840,76 @@ func buildKeyValueFields(field *surface_v1.Field) []*dpb.FieldDescriptorProto {
 	return []*dpb.FieldDescriptorProto{keyField, valueField}
 }
 
+// Builds an enum from the 'enum' values of 'schema'. Since enum values are siblings of the enum and not children of
+// it, all values are prefixed with the name of the enum to avoid collisions. (https://developers.google.com/protocol-buffers/docs/style#enums)
+// The values of integer enums keep their numbers (e.g.: 'CODE_10 = 10' for 10), so that the numbers on the wire and
+// inside of JSON are the values of the OpenAPI description. proto3 requires the first enum value to be zero, so a
+// value 0 comes first and <NAME>_UNSPECIFIED = 0 is added if there is none. The values of string enums are numbered
+// in order after <NAME>_UNSPECIFIED. The JSON mapping uses their names (e.g.: 'STATUS_AVAILABLE' instead of
+// 'available'), the checker reports that.
+func buildEnumDescriptorProto(name string, schema *openapiv3.Schema) (*dpb.EnumDescriptorProto, error) {
+	prefix := strings.ToUpper(toSnakeCase(name))
+	enum := &dpb.EnumDescriptorProto{Name: &name}
+	usedNames := make(map[string]bool)
+	usedNumbers := make(map[int32]bool)
+	addValue := func(valueName string, number int32) {
+		// Different values might end up with the same name after removing invalid characters.
+		base := valueName
+		for i := 2; usedNames[valueName]; i++ {
+			valueName = base + "_" + strconv.Itoa(i)
+		}
+		usedNames[valueName] = true
+		usedNumbers[number] = true
+		value := &dpb.EnumValueDescriptorProto{Name: proto.String(valueName), Number: proto.Int32(number)}
+		if number == 0 {
+			enum.Value = append([]*dpb.EnumValueDescriptorProto{value}, enum.Value...)
+		} else {
+			enum.Value = append(enum.Value, value)
+		}
+	}
+
+	values := make([]string, 0)
+	for _, value := range schema.Enum {
+		s, ok := stringValueOfAny(value)
+		if !ok {
+			continue // 'null' is a valid enum value for nullable schemas, but it can't be represented as enum value.
+		}
+		values = append(values, s)
+	}
+
+	if schema.Type == "integer" {
+		for _, s := range values {
+			n, err := strconv.ParseInt(s, 10, 32)
+			if err != nil {
+				return nil, fmt.Errorf("the value '%s' of the enum %s is not a 32-bit integer", s, name)
+			}
+			number := int32(n)
+			if usedNumbers[number] {
+				continue
+			}
+			valueName := prefix + "_" + strconv.Itoa(int(number))
+			if number < 0 {
+				valueName = prefix + "_MINUS_" + strconv.Itoa(int(-number))
+			}
+			addValue(valueName, number)
+		}
+		if !usedNumbers[0] {
+			addValue(prefix+"_UNSPECIFIED", 0)
+		}
+		return enum, nil
+	}
+
+	addValue(prefix+"_UNSPECIFIED", 0)
+	for _, s := range values {
+		valueName := prefix + "_" + strings.ToUpper(toSnakeCase(s))
+		if toSnakeCase(s) == "" {
+			valueName = prefix + "_EMPTY"
+		}
+		addValue(valueName, int32(len(enum.Value)))
+	}
+	return enum, nil
+}
+
 // Validates if the path parameter has the requested structure.
 // This is necessary according to: https://github.com/googleapis/googleapis/blob/master/google/api/http.proto#L62
 func validatePathParameter(field *surface_v1.Field) {
@@ -343,6 +935,27 @@ func validateQueryParameter(field *surface_v1.Field) {
 
 }
 
+// Returns the value of the discriminator property that selects 'typeName' as variant. According to the specification
+// (https://swagger.io/specification/#discriminatorObject) the name of the schema is used if there is no mapping.
+func getDiscriminatorValue(discriminator *openapiv3.Discriminator, typeName string) string {
+	for _, pair := range discriminator.GetMapping().GetAdditionalProperties() {
+		if path.Base(pair.Value) == typeName {
+			return pair.Name
+		}
+	}
+	return typeName
+}
+
+// Returns true if 'message' already has a field named 'name'.
+func hasFieldWithName(message *dpb.DescriptorProto, name string) bool {
+	for _, fd := range message.Field {
+		if *fd.Name == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Checks whether 't' is a type that will be used as a request parameter for a RPC method.
 func isRequestParameter(t *surface_v1.Type) bool {
 	if strings.Contains(t.Description, t.GetName()+" holds parameters to") {
@@ -356,26 +969,75 @@ func setFieldDescriptorType(fd *dpb.FieldDescriptorProto, f *surface_v1.Field) {
 	var protoType dpb.FieldDescriptorProto_Type
 	if t, ok := protoBufScalarTypes[f.Format]; ok { // Let's see if we can get the type from f.format
 		protoType = t
+	} else if f.Format == "binary" { // Binary data (e.g.: a file inside of a multipart form).
+		protoType = dpb.FieldDescriptorProto_TYPE_BYTES
 	} else if t, ok := protoBufScalarTypes[f.Type]; ok { // Maybe this works.
 		protoType = t
 	} else if t, ok := openAPITypesToProtoBuf[f.Type]; ok { // Safety check
 		protoType = t
 	} else {
-		// TODO: What about Enums?
 		// Ok, is it either a reference or an array of non scalar-types or a map. All of those get represented as message
-		// inside the descriptor.
+		// inside the descriptor. References to enums are handled by setFieldDescriptorEnumType.
 		protoType = dpb.FieldDescriptorProto_TYPE_MESSAGE
 	}
 	fd.Type = &protoType
 
 }
 
-// Sets the Name of 'fd'. The convention inside .proto is, that all field names are
-// lowercase and all messages and types are capitalized if they are not scalar types (int64, string, ...).
+// Changes 'fd' into a field that has the enum 'typeName' as type.
+func setFieldDescriptorEnumType(fd *dpb.FieldDescriptorProto, typeName string) {
+	protoType := dpb.FieldDescriptorProto_TYPE_ENUM
+	fd.Type = &protoType
+	fd.TypeName = &typeName
+}
+
+// Sets the Name of 'fd'. The convention inside .proto is, that all field names are lower snake case
+// (e.g.: 'photo_urls') and all messages and types are capitalized if they are not scalar types (int64, string, ...).
+// The JSON name keeps the name of the property or parameter (e.g.: 'photoUrls'), so that the JSON mapping of
+// gRPC-HTTP transcoding matches the REST payload.
 func setFieldDescriptorName(fd *dpb.FieldDescriptorProto, f *surface_v1.Field) {
-	name := cleanName(f.Name)
-	name = strings.ToLower(name)
+	name := getFieldName(f.Name)
 	fd.Name = &name
+	if hasJSONName(f) {
+		jsonName := f.Name
+		fd.JsonName = &jsonName
+	}
+}
+
+// Returns the name of the field for the surface model field 'name' (e.g.: 'photo_urls' for 'photoUrls').
+func getFieldName(name string) string {
+	return toSnakeCase(cleanName(name))
+}
+
+// Returns true if 'f' is a property or parameter of the REST payload. The fields that the surface model creates for
+// request bodies, responses, media types, additional properties, polymorphic schemas and references to parameters of
+// the components (they are named after the component) have no counterpart inside of the payload, they keep the
+// default JSON name.
+func hasJSONName(f *surface_v1.Field) bool {
+	switch {
+	case f.Name == "request_body" || f.Name == "additional_properties":
+		return false
+	case f.Kind == surface_v1.FieldKind_REFERENCE && f.Name == f.Type:
+		return false
+	case convertStatusCodes(f.Name) != f.Name || strings.Contains(f.Name, "/"):
+		return false
+	case strings.HasPrefix(f.Name, "one_of_") || strings.HasPrefix(f.Name, "any_of_") || isAllOfField(f):
+		return false
+	}
+	return true
+}
+
+// Returns 'path' with the variables of the path template renamed after the fields of the path parameters
+// (e.g.: '/pets/{pet_id}' for '/pets/{petId}'). 'fieldNames' are the names of the generated fields by the names of
+// the parameters (see: x-proto-name). Variables without a generated field (e.g.: undeclared path parameters) are kept.
+func getPathTemplate(path string, fieldNames map[string]string) string {
+	return pathVariablePattern.ReplaceAllStringFunc(path, func(variable string) string {
+		name := strings.Trim(variable, "{}")
+		if fieldName, ok := fieldNames[name]; ok {
+			return "{" + fieldName + "}"
+		}
+		return variable
+	})
 }
 
 // Sets a Label for 'fd'. If it is an array we need the 'repeated' label.
@@ -390,13 +1052,14 @@ func setFieldDescriptorLabel(fd *dpb.FieldDescriptorProto, f *surface_v1.Field)
 // Sets the TypeName of 'fd'. A TypeName has to be set if the field is a reference to another message. Otherwise it is nil.
 // The convention inside .proto is, that all field names are lowercase and all messages and types are capitalized if
 // they are not scalar types (int64, string, ...).
-func setFieldDescriptorTypeName(fd *dpb.FieldDescriptorProto, f *surface_v1.Field, packageName string) {
+func setFieldDescriptorTypeName(fd *dpb.FieldDescriptorProto, f *surface_v1.Field, packageName string, schemas *schemaIndex, generatedMessages map[string]string) {
 	// A field with a type of Message always has a typeName associated with it (the name of the Message).
 	if *fd.Type == dpb.FieldDescriptorProto_TYPE_MESSAGE {
-		typeName := packageName + "." + cleanTypeName(f.Type)
+		typeName := packageName + "." + schemas.protoTypeName(f.Type)
 
 		// Check whether we generated this message already inside of another dependency. If so we will use that name instead.
-		if n, ok := generatedMessages[f.Type]; ok {
+		// Messages of this package are always named by the schema index: their names were made unique.
+		if n, ok := generatedMessages[f.Type]; ok && !strings.HasPrefix(n, packageName+".") {
 			typeName = n
 		}
 		fd.TypeName = &typeName
@@ -404,8 +1067,9 @@ func setFieldDescriptorTypeName(fd *dpb.FieldDescriptorProto, f *surface_v1.Fiel
 }
 
 // Finds the corresponding surface model type for 'name' and returns the name of the field
-// that is a request body. If no such field is found it returns nil.
-func getRequestBodyForRequestParameters(name string, types []*surface_v1.Type) *string {
+// that is a request body. If no such field is found it returns nil. The surface model puts cookie parameters inside
+// of the body as well, they are skipped.
+func getRequestBodyForRequestParameters(name string, types []*surface_v1.Type, schemas *schemaIndex) *string {
 	requestParameterType := &surface_v1.Type{}
 
 	for _, t := range types {
@@ -415,46 +1079,63 @@ func getRequestBodyForRequestParameters(name string, types []*surface_v1.Type) *
 	}
 
 	for _, f := range requestParameterType.Fields {
-		if f.Position == surface_v1.Position_BODY {
-			return &f.Name
+		if f.Position == surface_v1.Position_BODY && !isMetadataParameter(schemas.parameterLocation(name, f.Name)) {
+			fieldName := getFieldName(f.Name)
+			return &fieldName
 		}
 	}
 	return nil
 }
 
+// Finds the corresponding surface model type for 'name' and returns the name of the field that is a binary response
+// body (google.api.HttpBody). If no such field is found it returns an empty string.
+func getResponseBodyForResponses(name string, types []*surface_v1.Type, schemas *schemaIndex) string {
+	for _, t := range types {
+		if t.Name != name {
+			continue
+		}
+		for _, f := range t.Fields {
+			if typeName, ok := schemas.replacedType(f.Type); ok && typeName == "google.api.HttpBody" {
+				return getFieldName(f.Name)
+			}
+		}
+	}
+	return ""
+}
+
 // Constructs a HttpRule from google/api/http.proto. Enables gRPC-HTTP transcoding on 'method'.
-// If not nil, body is also set.
-func getHttpRuleForMethod(method *surface_v1.Method, body *string) annotations.HttpRule {
+// If not nil, body is also set. 'fieldNames' are the names of the fields of the parameters message.
+func getHttpRuleForMethod(method *surface_v1.Method, body *string, fieldNames map[string]string) annotations.HttpRule {
 	var httpRule annotations.HttpRule
 	switch method.Method {
 	case "GET":
 		httpRule = annotations.HttpRule{
 			Pattern: &annotations.HttpRule_Get{
-				Get: method.Path,
+				Get: getPathTemplate(method.Path, fieldNames),
 			},
 		}
 	case "POST":
 		httpRule = annotations.HttpRule{
 			Pattern: &annotations.HttpRule_Post{
-				Post: method.Path,
+				Post: getPathTemplate(method.Path, fieldNames),
 			},
 		}
 	case "PUT":
 		httpRule = annotations.HttpRule{
 			Pattern: &annotations.HttpRule_Put{
-				Put: method.Path,
+				Put: getPathTemplate(method.Path, fieldNames),
 			},
 		}
 	case "PATCH":
 		httpRule = annotations.HttpRule{
 			Pattern: &annotations.HttpRule_Patch{
-				Patch: method.Path,
+				Patch: getPathTemplate(method.Path, fieldNames),
 			},
 		}
 	case "DELETE":
 		httpRule = annotations.HttpRule{
 			Pattern: &annotations.HttpRule_Delete{
-				Delete: method.Path,
+				Delete: getPathTemplate(method.Path, fieldNames),
 			},
 		}
 	}
@@ -494,17 +1175,64 @@ func getProtoTypeForMapValueType(valueType string) *dpb.FieldDescriptorProto_Typ
 	return &protoType
 }
 
-// Uses the 'binaryInput' from gnostic to create a OpenAPI document.
-func createOpenAPIDocFromGnosticOutput(binaryInput []byte) (*openapiv3.Document, error) {
-	document := &openapiv3.Document{}
-	err := proto.Unmarshal(binaryInput, document)
+// Reads and compiles the OpenAPI description at 'location' (a file path or URL). References are not resolved here,
+// they are resolved when the surface model is built.
+func readOpenAPIDocument(location string) (*openapiv3.Document, error) {
+	compilerMutex.Lock()
+	defer compilerMutex.Unlock()
+
+	b, err := compiler.ReadBytesForFile(location)
 	if err != nil {
-		// If we execute gnostic with argument: '-pb-out=-' we get an EOF. So lets only return other errors.
-		if err.Error() != "unexpected EOF" {
-			return nil, err
+		return nil, err
+	}
+	info, err := compiler.ReadInfoFromBytes(location, b)
+	if err != nil {
+		return nil, err
+	}
+	// The surface model treats every entry inside of the info cache as symbolic reference, so the description itself
+	// must not stay inside of it.
+	compiler.RemoveFromInfoCache(location)
+
+	m, ok := compiler.UnpackMap(info)
+	if !ok {
+		return nil, fmt.Errorf("%s is not an OpenAPI description", location)
+	}
+	version, _ := compiler.MapValueForKey(m, "openapi").(string)
+	if !strings.HasPrefix(version, "3.") {
+		return nil, fmt.Errorf("%s is not an OpenAPI v3 description", location)
+	}
+	return openapiv3.NewDocument(info, compiler.NewContext("$root", nil))
+}
+
+// Builds the surface model of 'document', which was read from 'location'.
+func buildSurfaceModel(document *openapiv3.Document, location string) (*surface_v1.Model, error) {
+	compilerMutex.Lock()
+	defer compilerMutex.Unlock()
+	model, err := surface_v1.NewModelFromOpenAPI3(document, location)
+	if err != nil {
+		return nil, err
+	}
+	model.SymbolicReferences = removeSourceReferences(model.SymbolicReferences, location)
+	return model, nil
+}
+
+// gnostic considers every absolute path a symbolic reference, so if 'location' is an absolute path the document
+// references itself. Returns 'references' without the references to the document at 'location'.
+func removeSourceReferences(references []string, location string) []string {
+	if location == "" {
+		return references
+	}
+	source, err := filepath.Abs(location)
+	if err != nil {
+		return references
+	}
+	result := make([]string, 0)
+	for _, reference := range references {
+		if filepath.Clean(strings.Split(reference, "#")[0]) != source {
+			result = append(result, reference)
 		}
 	}
-	return document, nil
+	return result
 }
 
 // 'url' is a list of URLs to other OpenAPI descriptions. We need the base of all URLs and no duplicates.
@@ -519,6 +1247,16 @@ func trimAndRemoveDuplicates(urls []string) []string {
 	return result
 }
 
+// Returns true if a file with 'name' is inside 'files'.
+func containsFile(files []*dpb.FileDescriptorProto, name string) bool {
+	for _, fd := range files {
+		if fd.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
+
 // Returns true if 's' is inside 'ss'.
 func isDuplicate(ss []string, s string) bool {
 	for _, s2 := range ss {
@@ -529,6 +1267,16 @@ func isDuplicate(ss []string, s string) bool {
 	return false
 }
 
+// Returns the surface model type with the name 'name' or nil.
+func findType(types []*surface_v1.Type, name string) *surface_v1.Type {
+	for _, t := range types {
+		if t.Name == name {
+			return t
+		}
+	}
+	return nil
+}
+
 // returns the last FileDescriptorProto of the array 'protos'.
 func getLast(protos []*dpb.FileDescriptorProto) *dpb.FileDescriptorProto {
 	return protos[len(protos)-1]
@@ -581,7 +1329,6 @@ func getOpenAPIScalarTypes() map[string]bool {
 
 // Sets the name of the 'messageDescriptorProto'
 func setMessageDescriptorName(messageDescriptorProto *dpb.DescriptorProto, name string) {
-	name = cleanTypeName(name)
 	messageDescriptorProto.Name = &name
 }
 
@@ -595,6 +1342,7 @@ func cleanName(name string) string {
 	name = strings.Replace(name, "{", "", -1)
 	name = strings.Replace(name, "}", "", -1)
 	name = strings.Replace(name, "/", "_", -1)
+	name = strings.Replace(name, ".", "_", -1)
 	name = strings.Replace(name, "$", "", -1)
 	return name
 }
@@ -0,0 +1,234 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// FieldNumberLock records the field numbers that have been assigned to the fields of the generated messages.
+// Without it, field numbers depend on the position of a property inside of the OpenAPI description, so adding or
+// reordering properties would silently break wire compatibility with deployed clients.
+type FieldNumberLock struct {
+	// Locked messages by their fully qualified name (e.g.: 'bookstore.Book').
+	Messages map[string]*MessageLock `json:"messages"`
+}
+
+// MessageLock records the field numbers of a single message.
+type MessageLock struct {
+	// Field numbers by field name.
+	Fields map[string]int32 `json:"fields"`
+	// Numbers and names of removed fields. They will never be assigned again.
+	ReservedNumbers []int32  `json:"reserved_numbers,omitempty"`
+	ReservedNames   []string `json:"reserved_names,omitempty"`
+}
+
+// NewFieldNumberLock creates an empty lock.
+func NewFieldNumberLock() *FieldNumberLock {
+	return &FieldNumberLock{Messages: make(map[string]*MessageLock)}
+}
+
+// ReadFieldNumberLock reads the lock file at 'path'. If the file does not exist yet, an empty lock is returned.
+func ReadFieldNumberLock(path string) (*FieldNumberLock, error) {
+	b, err := ioutil.ReadFile(path)
+	if os.IsNotExist(err) {
+		return NewFieldNumberLock(), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	lock := NewFieldNumberLock()
+	if err := json.Unmarshal(b, lock); err != nil {
+		return nil, err
+	}
+	if lock.Messages == nil {
+		lock.Messages = make(map[string]*MessageLock)
+	}
+	return lock, nil
+}
+
+// Write writes the lock to 'path'. The output is deterministic, so the lock file can be checked in.
+func (lock *FieldNumberLock) Write(path string) error {
+	b, err := json.MarshalIndent(lock, "", "  ")
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, append(b, '\n'), 0644)
+}
+
+// Assigns the locked field numbers to all messages of 'fd' and records the numbers of new fields. Fields that are
+// inside of the lock but not inside of the message anymore get reserved. The numbers of 'pinned' fields
+// (see: x-proto-field-number) are not changed, they overwrite the lock instead.
+func (lock *FieldNumberLock) apply(fd *dpb.FileDescriptorProto, pinned map[*dpb.FieldDescriptorProto]bool) error {
+	for _, message := range fd.MessageType {
+		err := lock.applyToMessage(fd.GetPackage()+"."+message.GetName(), message, pinned)
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (lock *FieldNumberLock) applyToMessage(fullName string, message *dpb.DescriptorProto, pinned map[*dpb.FieldDescriptorProto]bool) error {
+	messageLock, ok := lock.Messages[fullName]
+	if !ok {
+		messageLock = &MessageLock{Fields: make(map[string]int32)}
+		lock.Messages[fullName] = messageLock
+	}
+	if messageLock.Fields == nil {
+		messageLock.Fields = make(map[string]int32)
+	}
+
+	// The highest number that was ever used inside of this message.
+	var maxNumber int32
+	for _, n := range messageLock.Fields {
+		maxNumber = maxInt32(maxNumber, n)
+	}
+	for _, n := range messageLock.ReservedNumbers {
+		maxNumber = maxInt32(maxNumber, n)
+	}
+
+	// Pinned numbers are taken, no matter what the lock says.
+	used := make(map[int32]bool)
+	for _, field := range message.Field {
+		if pinned[field] {
+			if containsInt32(messageLock.ReservedNumbers, field.GetNumber()) {
+				return fmt.Errorf("field number %d of %s.%s is reserved", field.GetNumber(), fullName, field.GetName())
+			}
+			used[field.GetNumber()] = true
+			maxNumber = maxInt32(maxNumber, field.GetNumber())
+		}
+	}
+
+	present := make(map[string]bool)
+	for _, field := range message.Field {
+		name := field.GetName()
+		present[name] = true
+		migrateLegacyFieldName(messageLock, field)
+		if pinned[field] {
+			messageLock.Fields[name] = field.GetNumber()
+			messageLock.ReservedNames = removeString(messageLock.ReservedNames, name)
+			continue
+		}
+		if n, ok := messageLock.Fields[name]; ok && !used[n] {
+			field.Number = &n
+			used[n] = true
+			continue
+		}
+		// A new field (or a field that got removed and added again). It always gets a new number.
+		n, ok := nextFieldNumber(maxNumber)
+		if !ok {
+			return fmt.Errorf("no field number left for %s.%s", fullName, name)
+		}
+		maxNumber = n
+		field.Number = &n
+		messageLock.Fields[name] = n
+		messageLock.ReservedNames = removeString(messageLock.ReservedNames, name)
+	}
+
+	for name, n := range messageLock.Fields {
+		if !present[name] {
+			delete(messageLock.Fields, name)
+			if !used[n] { // The number might have been taken over by a pinned field.
+				messageLock.ReservedNumbers = append(messageLock.ReservedNumbers, n)
+			}
+			messageLock.ReservedNames = append(messageLock.ReservedNames, name)
+		}
+	}
+	sort.Slice(messageLock.ReservedNumbers, func(i, j int) bool {
+		return messageLock.ReservedNumbers[i] < messageLock.ReservedNumbers[j]
+	})
+	sort.Strings(messageLock.ReservedNames)
+
+	message.ReservedRange = buildReservedRanges(messageLock.ReservedNumbers)
+	message.ReservedName = append([]string(nil), messageLock.ReservedNames...)
+	return nil
+}
+
+// Returns the first valid field number after 'n' or false if there is none.
+func nextFieldNumber(n int32) (int32, bool) {
+	for n < maxFieldNumber {
+		n++
+		if isValidFieldNumber(n) {
+			return n, true
+		}
+	}
+	return 0, false
+}
+
+// Field names used to be the lowercased names of the OpenAPI description (e.g.: 'photourls' instead of 'photo_urls').
+// A number that is locked under the old name of 'field' is moved to its current name, so that it stays stable.
+func migrateLegacyFieldName(messageLock *MessageLock, field *dpb.FieldDescriptorProto) {
+	name := field.GetName()
+	if _, ok := messageLock.Fields[name]; ok || field.JsonName == nil {
+		return
+	}
+	legacyName := strings.ToLower(cleanName(field.GetJsonName()))
+	if n, ok := messageLock.Fields[legacyName]; ok && legacyName != name {
+		messageLock.Fields[name] = n
+		delete(messageLock.Fields, legacyName)
+	}
+}
+
+// Combines the sorted 'numbers' into ranges. The end of a reserved range is exclusive.
+func buildReservedRanges(numbers []int32) []*dpb.DescriptorProto_ReservedRange {
+	ranges := make([]*dpb.DescriptorProto_ReservedRange, 0)
+	for _, n := range numbers {
+		if len(ranges) > 0 && ranges[len(ranges)-1].GetEnd() == n {
+			end := n + 1
+			ranges[len(ranges)-1].End = &end
+			continue
+		}
+		start, end := n, n+1
+		ranges = append(ranges, &dpb.DescriptorProto_ReservedRange{Start: &start, End: &end})
+	}
+	return ranges
+}
+
+// Returns 'ss' without 's'.
+func removeString(ss []string, s string) []string {
+	result := make([]string, 0)
+	for _, s2 := range ss {
+		if s2 != s {
+			result = append(result, s2)
+		}
+	}
+	return result
+}
+
+// Returns true if 'ns' contains 'n'.
+func containsInt32(ns []int32, n int32) bool {
+	for _, n2 := range ns {
+		if n == n2 {
+			return true
+		}
+	}
+	return false
+}
+
+func maxInt32(a, b int32) int32 {
+	if a > b {
+		return a
+	}
+	return b
+}
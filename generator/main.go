@@ -19,8 +19,10 @@ import (
 	"errors"
 	"go/format"
 	"path/filepath"
+	"strconv"
 
 	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
 	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
 	plugins "github.com/googleapis/gnostic/plugins"
 	surface "github.com/googleapis/gnostic/surface"
@@ -38,14 +40,73 @@ func RunProtoGenerator() {
 	packageName, err := resolvePackageName(fileName)
 	env.RespondAndExitIfError(err)
 
+	var lockFile, descriptorSetOut string
+	var includeImports, timeTypes, validateRules, servicePerTag, splitFiles, metadataHelper bool
+	var optionalFields string
+	fileOptions := &dpb.FileOptions{}
+	for _, parameter := range env.Request.Parameters {
+		switch parameter.Name {
+		case "field_number_lock":
+			// Path to a lock file that keeps the field numbers stable between runs.
+			lockFile = parameter.Value
+		case "descriptor_set_out", "descriptor_set":
+			// Either 'true' or the name of the FileDescriptorSet (e.g.: 'api.pb'). gnostic mistakes every argument
+			// that contains '_out=' for the output directory of another plugin, so on its command line the
+			// parameter has to be called 'descriptor_set'.
+			switch parameter.Value {
+			case "true":
+				descriptorSetOut = packageName + ".descr"
+			case "false":
+				descriptorSetOut = ""
+			default:
+				descriptorSetOut = parameter.Value
+			}
+		case "include_imports":
+			includeImports, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "optional_fields":
+			// Only 'wrappers' is supported, 'proto3_optional' is rejected with an error.
+			optionalFields = parameter.Value
+			env.RespondAndExitIfError(validateOptionalFieldsStrategy(optionalFields))
+		case "time_types":
+			// Use well-known types for 'date-time', 'date' and 'duration'.
+			timeTypes, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "validate_rules":
+			// Attach protoc-gen-validate rules for the constraints of the OpenAPI description.
+			validateRules, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "service_per_tag":
+			// Group the operations into one service per tag.
+			servicePerTag, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "split_files":
+			// Split the main .proto into a file with the shared types and one file per service.
+			splitFiles, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "metadata_helper":
+			// Render a Go header matcher for grpc-gateway that forwards header and cookie parameters.
+			metadataHelper, err = strconv.ParseBool(parameter.Value)
+			env.RespondAndExitIfError(err)
+		case "go_package", "java_package", "java_multiple_files", "objc_class_prefix", "php_namespace", "csharp_namespace":
+			// File options of the generated .proto.
+			env.RespondAndExitIfError(setFileOption(fileOptions, parameter.Name, parameter.Value))
+		}
+	}
+
+	var openAPIdocument *openapiv3.Document
 	for _, model := range env.Request.Models {
 		switch model.TypeUrl {
 		case "openapi.v3.Document":
-			openAPIdocument := &openapiv3.Document{}
-			err := proto.Unmarshal(model.Value, openAPIdocument)
+			document := &openapiv3.Document{}
+			err := proto.Unmarshal(model.Value, document)
 
 			if err == nil {
+				openAPIdocument = document
 				featureChecker := NewGrpcChecker(openAPIdocument)
+				featureChecker.ValidateRules = validateRules
+				featureChecker.ServicePerTag = servicePerTag
+				featureChecker.OptionalFields = optionalFields
 				env.Response.Messages = featureChecker.Run()
 			}
 		case "surface.v1.Model":
@@ -55,11 +116,30 @@ func RunProtoGenerator() {
 				// Create the renderer.
 				renderer := NewRenderer(surfaceModel)
 				renderer.Package = packageName
+				renderer.Document = openAPIdocument
+				renderer.DescriptorSetOut = descriptorSetOut
+				renderer.IncludeImports = includeImports
+				renderer.TimeTypes = timeTypes
+				renderer.OptionalFields = optionalFields
+				renderer.ValidateRules = validateRules
+				renderer.ServicePerTag = servicePerTag
+				renderer.SplitFiles = splitFiles
+				renderer.FileOptions = fileOptions
+				renderer.MetadataHelper = metadataHelper
+				if lockFile != "" {
+					renderer.FieldNumberLock, err = ReadFieldNumberLock(lockFile)
+					env.RespondAndExitIfError(err)
+				}
 
 				// Run the renderer to generate files and add them to the response object.
 				err = renderer.Render(env.Response, packageName+".proto")
 				env.RespondAndExitIfError(err)
 
+				if lockFile != "" {
+					err = renderer.FieldNumberLock.Write(lockFile)
+					env.RespondAndExitIfError(err)
+				}
+
 				// Return with success.
 				env.RespondAndExit()
 			}
@@ -0,0 +1,173 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"go/format"
+	"path"
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gnostic-grpc/metadata"
+	plugins "github.com/googleapis/gnostic/plugins"
+)
+
+// Header and cookie parameters are not part of the request messages, since gRPC-HTTP transcoders never populate
+// them. They are sent as gRPC metadata instead. Which metadata keys carry them is recorded inside of the method option
+// '(gnostic.grpc.metadata)'. The option is defined inside of proto/gnostic/grpc/metadata.proto of this repository,
+// which the generated .proto files import.
+
+// Returns true if parameters with the location 'location' are sent as gRPC metadata.
+func isMetadataParameter(location string) bool {
+	return location == "header" || location == "cookie"
+}
+
+// Builds the description of the header or cookie parameter 'name'. gRPC metadata keys are lowercase. Cookies are
+// carried by the 'cookie' header, so the name of the cookie is recorded as well.
+func buildMetadataParameter(name string, location string, required bool) *metadata.MetadataParameter {
+	parameter := &metadata.MetadataParameter{Name: name, Key: strings.ToLower(name), Location: location, Required: required}
+	if location == "cookie" {
+		parameter.Key = "cookie"
+		parameter.Cookie = name
+	}
+	return parameter
+}
+
+// Sets the option '(gnostic.grpc.metadata)' of 'method'.
+func setMethodMetadata(method *dpb.MethodDescriptorProto, parameters []*metadata.MetadataParameter) error {
+	if len(parameters) == 0 {
+		return nil
+	}
+	if method.Options == nil {
+		method.Options = &dpb.MethodOptions{}
+	}
+	return proto.SetExtension(method.Options, metadata.E_Metadata, &metadata.MethodMetadata{Parameters: parameters})
+}
+
+// Returns the FileDescriptorProto of the file that defines the option '(gnostic.grpc.metadata)'.
+func getMetadataFile() *dpb.FileDescriptorProto {
+	fd, _ := decompressFileDescriptor(proto.FileDescriptor(metadata.E_Metadata.Filename))
+	return fd
+}
+
+// Renders a Go file for the package 'goPackage' with a header matcher for grpc-gateway
+// (runtime.WithIncomingHeaderMatcher) that forwards the headers of all metadata parameters of 'fd' as gRPC metadata.
+// Returns nil if 'fd' has no metadata parameters.
+func renderMetadataHelper(fd *dpb.FileDescriptorProto, goPackage string, fileName string) *plugins.File {
+	keysByMethod := make(map[string][]string)
+	allKeys := make(map[string]bool)
+	for _, service := range fd.Service {
+		for _, method := range service.Method {
+			if method.Options == nil || !proto.HasExtension(method.Options, metadata.E_Metadata) {
+				continue
+			}
+			extension, err := proto.GetExtension(method.Options, metadata.E_Metadata)
+			if err != nil {
+				continue
+			}
+			fullName := "/" + fd.GetPackage() + "." + service.GetName() + "/" + method.GetName()
+			for _, parameter := range extension.(*metadata.MethodMetadata).Parameters {
+				// All cookies of a method are carried by the same key.
+				if !isDuplicate(keysByMethod[fullName], parameter.Key) {
+					keysByMethod[fullName] = append(keysByMethod[fullName], parameter.Key)
+				}
+				allKeys[parameter.Key] = true
+			}
+		}
+	}
+	if len(keysByMethod) == 0 {
+		return nil
+	}
+
+	methods := make([]string, 0)
+	for method := range keysByMethod {
+		methods = append(methods, method)
+	}
+	sort.Strings(methods)
+	keys := make([]string, 0)
+	for key := range allKeys {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	f := NewLineWriter()
+	f.WriteLine("// Code generated by gnostic-grpc. DO NOT EDIT.")
+	f.WriteLine("")
+	f.WriteLine("package " + goPackage)
+	f.WriteLine("")
+	f.WriteLine("import \"strings\"")
+	f.WriteLine("")
+	f.WriteLine("// MetadataKeys are the gRPC metadata keys that carry the header and cookie parameters of a method.")
+	f.WriteLine("var MetadataKeys = map[string][]string{")
+	for _, method := range methods {
+		quoted := make([]string, 0)
+		for _, key := range keysByMethod[method] {
+			quoted = append(quoted, `"`+key+`"`)
+		}
+		f.WriteLine("\t\"" + method + "\": {" + strings.Join(quoted, ", ") + "},")
+	}
+	f.WriteLine("}")
+	f.WriteLine("")
+	f.WriteLine("var metadataHeaders = map[string]bool{")
+	for _, key := range keys {
+		f.WriteLine("\t\"" + key + "\": true,")
+	}
+	f.WriteLine("}")
+	f.WriteLine("")
+	f.WriteLine("// IncomingHeaderMatcher forwards the headers of header and cookie parameters as gRPC metadata. It can be passed")
+	f.WriteLine("// to runtime.WithIncomingHeaderMatcher of grpc-gateway.")
+	f.WriteLine("func IncomingHeaderMatcher(key string) (string, bool) {")
+	f.WriteLine("\tkey = strings.ToLower(key)")
+	f.WriteLine("\tif metadataHeaders[key] {")
+	f.WriteLine("\t\treturn key, true")
+	f.WriteLine("\t}")
+	f.WriteLine("\treturn \"\", false")
+	f.WriteLine("}")
+
+	// The helper is formatted like every other Go source.
+	data, err := format.Source(f.Bytes())
+	if err != nil {
+		data = f.Bytes()
+	}
+	return &plugins.File{Name: fileName, Data: data}
+}
+
+// Returns the name of the Go package of 'fd': the name given by the option 'go_package' (e.g.: 'petstorepb' for
+// 'github.com/acme/petstore;petstorepb' or 'petstore' for 'github.com/acme/petstore') or else the name derived from
+// the .proto package.
+func getGoPackageName(fd *dpb.FileDescriptorProto) string {
+	goPackage := fd.GetOptions().GetGoPackage()
+	if goPackage == "" {
+		return goPackageName(fd.GetPackage())
+	}
+	if i := strings.LastIndex(goPackage, ";"); i >= 0 {
+		return goPackage[i+1:]
+	}
+	return goPackageName(path.Base(goPackage))
+}
+
+// Returns the name of the Go package for the .proto package 'packageName' (e.g.: 'bookstore' for 'example.bookstore').
+func goPackageName(packageName string) string {
+	name := packageName[strings.LastIndex(packageName, ".")+1:]
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return unicode.ToLower(r)
+		}
+		return -1
+	}, name)
+}
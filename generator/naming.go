@@ -0,0 +1,174 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strconv"
+	"strings"
+	"unicode"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	surface_v1 "github.com/googleapis/gnostic/surface"
+)
+
+// Prefixes for names that would otherwise start with a digit (e.g.: 'Rpc2faVerify' for the operationId '2fa-verify'
+// and 'Tag2faService' for the tag '2fa').
+const (
+	rpcNamePrefix     = "Rpc"
+	serviceNamePrefix = "Tag"
+)
+
+// Assigns the names of the services and RPCs of a file. Names are UpperCamelCase, so they are valid identifiers and
+// never collide with proto keywords, which are lowercase. Every name is unique inside of the file and differs from
+// the names of the top-level messages and enums: a service shares its scope with the messages and the input and
+// output types of methods are resolved relative to the service, so an RPC with the name of a message would hide the
+// message.
+type rpcNamer struct {
+	used map[string]bool
+}
+
+// Creates a namer for the services and RPCs of 'fd'. The messages and enums of 'fd' have to be built already.
+func newRPCNamer(fd *dpb.FileDescriptorProto) *rpcNamer {
+	namer := &rpcNamer{used: make(map[string]bool)}
+	for _, message := range fd.MessageType {
+		namer.used[message.GetName()] = true
+	}
+	for _, enum := range fd.EnumType {
+		namer.used[enum.GetName()] = true
+	}
+	return namer
+}
+
+// Returns the name of the RPC for 'method'. It is derived from the operationId (e.g.: 'ListPets' for 'list_pets') or,
+// if the operation has none, from the HTTP verb and the path (e.g.: 'GetPetsPetId' for 'GET /pets/{petId}').
+// Collisions are resolved by appending a number, starting with 2, in the order the methods are named.
+func (namer *rpcNamer) name(method *surface_v1.Method) string {
+	name := toUpperCamelCase(method.Operation)
+	if name == "" {
+		name = toUpperCamelCase(strings.ToLower(method.Method) + " " + method.Path)
+	}
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = rpcNamePrefix + name
+	}
+	return namer.unique(name)
+}
+
+// Returns 'name' as name of a service. Collisions are resolved like the ones of RPCs.
+func (namer *rpcNamer) serviceName(name string) string {
+	return namer.unique(name)
+}
+
+// Returns 'name' or, if it is already taken, 'name' with the first number (starting with 2) that makes it unique.
+func (namer *rpcNamer) unique(name string) string {
+	unique := name
+	for i := 2; namer.used[unique]; i++ {
+		unique = name + strconv.Itoa(i)
+	}
+	namer.used[unique] = true
+	return unique
+}
+
+// Returns the operationIds that the RPCs and messages of the operations of 'document' are named after. gnostic names
+// the messages of an operation after its operationId, so:
+//   - Operations without an operationId get one from the HTTP verb and the path (e.g.: 'GetPetsPetId' for
+//     'GET /pets/{petId}'), which is also the name of their RPC.
+//   - An operationId that is used by several operations is kept by the first one, the others get the first number
+//     (starting with 2) that makes it unique (e.g.: 'listPets2').
+func getOperationIds(document *openapiv3.Document) map[*openapiv3.Operation]string {
+	operationIds := make(map[*openapiv3.Operation]string)
+	if document.GetPaths() == nil {
+		return operationIds
+	}
+	// gnostic considers operationIds equal that only differ in the case of their first letter or in '.' and '_'.
+	key := func(operationId string) string {
+		return strings.Replace(strings.Title(operationId), ".", "_", -1)
+	}
+	reserved := make(map[string]bool)
+	for _, pair := range document.Paths.Path {
+		for _, operation := range getPathItemOperations(pair.Value) {
+			if operation != nil && operation.OperationId != "" {
+				reserved[key(operation.OperationId)] = true
+			}
+		}
+	}
+
+	used := make(map[string]bool)
+	for _, pair := range document.Paths.Path {
+		for i, operation := range getPathItemOperations(pair.Value) {
+			if operation == nil {
+				continue
+			}
+			name := operation.OperationId
+			available := name != "" && !used[key(name)]
+			if name == "" {
+				name = toUpperCamelCase(strings.ToLower(httpVerbs[i]) + " " + pair.Name)
+				available = !reserved[key(name)] && !used[key(name)]
+			}
+			unique := name
+			for i := 2; !available; i++ {
+				unique = name + strconv.Itoa(i)
+				available = !reserved[key(unique)] && !used[key(unique)]
+			}
+			used[key(unique)] = true
+			operationIds[operation] = unique
+		}
+	}
+	return operationIds
+}
+
+// The HTTP verbs in the order gnostic builds the methods of a path item.
+var httpVerbs = []string{"GET", "PUT", "POST", "DELETE", "OPTIONS", "HEAD", "PATCH", "TRACE"}
+
+// Returns the operations of 'pathItem' in the order of httpVerbs. The operations of unused verbs are nil.
+func getPathItemOperations(pathItem *openapiv3.PathItem) []*openapiv3.Operation {
+	if pathItem == nil {
+		return nil
+	}
+	return []*openapiv3.Operation{
+		pathItem.Get, pathItem.Put, pathItem.Post, pathItem.Delete,
+		pathItem.Options, pathItem.Head, pathItem.Patch, pathItem.Trace,
+	}
+}
+
+// Returns the name of the service for the operations with the tag 'tag' (e.g.: 'PetService' for 'pet' and
+// 'StoreOrdersService' for 'store orders').
+func getServiceNameForTag(tag string) string {
+	name := toUpperCamelCase(tag)
+	if name == "" || unicode.IsDigit(rune(name[0])) {
+		name = serviceNamePrefix + name
+	}
+	return name + "Service"
+}
+
+// Converts 'name' to UpperCamelCase: 'list_pets', 'list-pets' and 'listPets' become 'ListPets'. All characters that
+// are not allowed inside of a .proto identifier are treated as word boundaries and dropped. The case of the remaining
+// characters is kept (e.g.: 'getHTTPStatus' becomes 'GetHTTPStatus').
+func toUpperCamelCase(name string) string {
+	var b strings.Builder
+	startOfWord := true
+	for _, r := range name {
+		if r > unicode.MaxASCII || !(unicode.IsLetter(r) || unicode.IsDigit(r)) {
+			startOfWord = true
+			continue
+		}
+		if startOfWord {
+			r = unicode.ToUpper(r)
+			startOfWord = false
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
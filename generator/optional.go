@@ -0,0 +1,77 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"fmt"
+
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+)
+
+// Strategies for scalar fields that can be absent, because they are nullable or not required (see:
+// Renderer.OptionalFields). Proto3 scalars cannot distinguish an absent value from the zero value.
+const (
+	// Optional fields are rendered like all other fields.
+	OptionalFieldsNone = ""
+	// Optional fields use the wrapper types from google/protobuf/wrappers.proto (e.g.: google.protobuf.StringValue).
+	OptionalFieldsWrappers = "wrappers"
+	// Optional fields are marked with the proto3 'optional' label. The descriptor library this generator is built
+	// with (github.com/golang/protobuf v1.3.2) and protoprint predate the field 'proto3_optional' of
+	// FieldDescriptorProto, so this strategy is rejected with an error.
+	OptionalFieldsProto3 = "proto3_optional"
+)
+
+// Wrapper types of scalar types. Scalar types without a wrapper type (e.g.: sint32) are not changed.
+var wrapperTypes = map[dpb.FieldDescriptorProto_Type]string{
+	dpb.FieldDescriptorProto_TYPE_DOUBLE: "google.protobuf.DoubleValue",
+	dpb.FieldDescriptorProto_TYPE_FLOAT:  "google.protobuf.FloatValue",
+	dpb.FieldDescriptorProto_TYPE_INT64:  "google.protobuf.Int64Value",
+	dpb.FieldDescriptorProto_TYPE_UINT64: "google.protobuf.UInt64Value",
+	dpb.FieldDescriptorProto_TYPE_INT32:  "google.protobuf.Int32Value",
+	dpb.FieldDescriptorProto_TYPE_UINT32: "google.protobuf.UInt32Value",
+	dpb.FieldDescriptorProto_TYPE_BOOL:   "google.protobuf.BoolValue",
+	dpb.FieldDescriptorProto_TYPE_STRING: "google.protobuf.StringValue",
+	dpb.FieldDescriptorProto_TYPE_BYTES:  "google.protobuf.BytesValue",
+}
+
+// Returns an error if 'strategy' is not one of the strategies for optional fields.
+func validateOptionalFieldsStrategy(strategy string) error {
+	switch strategy {
+	case OptionalFieldsNone, OptionalFieldsWrappers:
+		return nil
+	case OptionalFieldsProto3:
+		return fmt.Errorf("the strategy '%s' for optional fields is not supported: the descriptor library "+
+			"(github.com/golang/protobuf v1.3.2) cannot represent proto3 optional fields, use '%s' instead",
+			OptionalFieldsProto3, OptionalFieldsWrappers)
+	}
+	return fmt.Errorf("unknown strategy for optional fields: '%s'", strategy)
+}
+
+// Applies 'strategy' to the 'optional' fields of 'message'. Repeated fields, messages and fields that are already
+// part of a oneof have presence anyway and are not changed.
+func buildOptionalFields(message *dpb.DescriptorProto, optional map[*dpb.FieldDescriptorProto]bool, strategy string) {
+	for _, fd := range message.Field {
+		if !optional[fd] || fd.OneofIndex != nil || fd.GetLabel() == dpb.FieldDescriptorProto_LABEL_REPEATED ||
+			fd.GetType() == dpb.FieldDescriptorProto_TYPE_MESSAGE {
+			continue
+		}
+
+		if strategy == OptionalFieldsWrappers {
+			if typeName, ok := wrapperTypes[fd.GetType()]; ok {
+				setFieldDescriptorMessageType(fd, typeName)
+			}
+		}
+	}
+}
@@ -15,8 +15,12 @@
 package generator
 
 import (
+	"strings"
+
 	"github.com/golang/protobuf/proto"
 	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gnostic-grpc/metadata"
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
 	plugins "github.com/googleapis/gnostic/plugins"
 	surface "github.com/googleapis/gnostic/surface"
 	prDesc "github.com/jhump/protoreflect/desc"
@@ -28,10 +32,58 @@ import (
 type Renderer struct {
 	// The model holds the necessary information from the OpenAPI description.
 	Model *surface.Model
+	// The OpenAPI description the model was built from. It is optional, but without it information that is not part
+	// of the surface model (e.g.: enums) is missing inside of the .proto.
+	Document *openapiv3.Document
 	// The FileDescriptorSet that will be printed with protoreflect
 	FdSet          *dpb.FileDescriptorSet
 	SymbolicFdSets []*dpb.FileDescriptorSet
 	Package        string // package name
+	// Name of the generated service. Defaults to the capitalized package name.
+	ServiceName string
+	// If set, field numbers are assigned according to the lock and new fields are recorded inside of it.
+	FieldNumberLock *FieldNumberLock
+	// If set, a binary FileDescriptorSet with this name is rendered next to the .proto (e.g.: 'bookstore.descr').
+	DescriptorSetOut string
+	// If true, the rendered FileDescriptorSet also contains all imported files, so that it is self-contained.
+	IncludeImports bool
+	// If true, strings with the format 'date-time', 'date' or 'duration' are represented by the well-known types
+	// google.protobuf.Timestamp, google.type.Date and google.protobuf.Duration.
+	TimeTypes bool
+	// Strategy for scalar fields that are nullable or not required: OptionalFieldsNone or OptionalFieldsWrappers.
+	OptionalFields string
+	// If true, the constraints of properties and parameters (e.g.: 'maximum', 'pattern' or 'minItems') are attached
+	// to the fields as protoc-gen-validate rules.
+	ValidateRules bool
+	// If true, the operations are grouped into one service per tag (e.g.: 'PetService' for the tag 'pet'). Operations
+	// with multiple tags belong to the service of their first tag, untagged operations to the default service.
+	ServicePerTag bool
+	// If true, the main .proto is split into '<package>_types.proto' with the shared messages and enums and one file
+	// per service (e.g.: 'bookstore_pet_service.proto'). The files of symbolic references are not split.
+	SplitFiles bool
+	// File options (e.g.: go_package or java_package) of the main .proto. They override the options of the vendor
+	// extension 'x-proto-options' of 'info'. The files of symbolic references only get the options of their own
+	// description.
+	FileOptions *dpb.FileOptions
+	// If true, a Go file '<package>_metadata.go' with a header matcher for grpc-gateway is rendered, that forwards the
+	// header and cookie parameters as gRPC metadata. It is only rendered if there are such parameters.
+	MetadataHelper bool
+
+	// The surface model of the current run of the generator: Model or a model with named operations (see:
+	// nameOperations).
+	model *surface.Model
+	// Connects the types of model with the schemas of Document.
+	schemas *schemaIndex
+	// Fields whose number was pinned with the vendor extension 'x-proto-field-number'.
+	pinnedFields map[*dpb.FieldDescriptorProto]bool
+	// Header and cookie parameters by the name of the surface model type of the method parameters.
+	metadataParameters map[string][]*metadata.MetadataParameter
+	// The state of the current run of the generator.
+	generation *generationContext
+	// Descriptions of messages, fields, enums and methods. They are rendered as comments.
+	descriptions map[proto.Message]string
+	// The files the main file is split into (if SplitFiles is set).
+	splitFiles []*dpb.FileDescriptorProto
 }
 
 // NewRenderer creates a renderer.
@@ -50,7 +102,7 @@ func (renderer *Renderer) Render(response *plugins.Response, fileName string) (e
 		return err
 	}
 
-	if false { //TODO: If we want to generate the descriptor file, we need an additional flag here!
+	if renderer.DescriptorSetOut != "" {
 		f, err := renderer.RenderDescriptor()
 		if err != nil {
 			return err
@@ -59,21 +111,39 @@ func (renderer *Renderer) Render(response *plugins.Response, fileName string) (e
 	}
 
 	// Render main proto definition.
-	f, err := renderer.RenderProto(renderer.FdSet, fileName)
-	if err != nil {
-		return err
+	if renderer.SplitFiles {
+		for _, fdSet := range renderer.buildSplitFileDescriptorSets() {
+			f, err := renderer.RenderProto(fdSet, getLast(fdSet.File).GetName())
+			if err != nil {
+				return err
+			}
+			response.Files = append(response.Files, f)
+		}
+	} else {
+		f, err := renderer.RenderProto(renderer.FdSet, fileName)
+		if err != nil {
+			return err
+		}
+		response.Files = append(response.Files, f)
 	}
-	response.Files = append(response.Files, f)
 
 	// Render external proto definitions.
 	for _, externalSet := range renderer.SymbolicFdSets {
-		f, err = renderer.RenderProto(externalSet, *getLast(externalSet.File).Name)
+		f, err := renderer.RenderProto(externalSet, *getLast(externalSet.File).Name)
 		if err != nil {
 			return err
 		}
 		response.Files = append(response.Files, f)
 	}
 
+	// Render a Go helper for grpc-gateway that forwards header and cookie parameters.
+	if renderer.MetadataHelper {
+		mainProto := getLast(renderer.FdSet.File)
+		if f := renderMetadataHelper(mainProto, getGoPackageName(mainProto), strings.Replace(fileName, ".proto", "_metadata.go", 1)); f != nil {
+			response.Files = append(response.Files, f)
+		}
+	}
+
 	return err
 }
 
@@ -100,13 +170,94 @@ func (renderer *Renderer) RenderProto(fdSet *dpb.FileDescriptorSet, fileName str
 	return file, err
 }
 
+// RenderDescriptor renders the generated .proto definitions as binary FileDescriptorSet, like protoc does with the
+// flag '--descriptor_set_out'. Dependencies always come before the files that import them.
 func (renderer *Renderer) RenderDescriptor() (*plugins.File, error) {
-	fdSetData, err := proto.Marshal(renderer.FdSet)
+	fdSetData, err := proto.Marshal(renderer.buildDescriptorSet())
 	if err != nil {
 		return nil, err
 	}
 
-	descriptorFile := &plugins.File{Name: renderer.Package + ".descr"}
+	name := renderer.DescriptorSetOut
+	if name == "" {
+		name = renderer.Package + ".descr"
+	}
+	descriptorFile := &plugins.File{Name: name}
 	descriptorFile.Data = fdSetData
 	return descriptorFile, nil
 }
+
+// Returns a FileDescriptorSet with the generated files (the main file or the files it is split into and the files of
+// symbolic references). If IncludeImports is set, all transitive dependencies are added as well.
+func (renderer *Renderer) buildDescriptorSet() *dpb.FileDescriptorSet {
+	files := make(map[string]*dpb.FileDescriptorProto)
+	for _, fdSet := range append([]*dpb.FileDescriptorSet{renderer.FdSet}, renderer.SymbolicFdSets...) {
+		for _, fd := range fdSet.File {
+			if _, ok := files[fd.GetName()]; !ok {
+				files[fd.GetName()] = fd
+			}
+		}
+	}
+
+	mainProtos := renderer.mainFiles()
+	generated := make(map[string]bool)
+	for _, mainProto := range mainProtos {
+		files[mainProto.GetName()] = mainProto
+		generated[mainProto.GetName()] = true
+	}
+	for _, symbolicFdSet := range renderer.SymbolicFdSets {
+		generated[getLast(symbolicFdSet.File).GetName()] = true
+	}
+
+	result := &dpb.FileDescriptorSet{}
+	visited := make(map[string]bool)
+	var visit func(fd *dpb.FileDescriptorProto)
+	visit = func(fd *dpb.FileDescriptorProto) {
+		if visited[fd.GetName()] {
+			return
+		}
+		visited[fd.GetName()] = true
+		for _, dependency := range fd.Dependency {
+			if dependencyFd, ok := files[dependency]; ok {
+				visit(dependencyFd)
+			}
+		}
+		if renderer.IncludeImports || generated[fd.GetName()] {
+			result.File = append(result.File, fd)
+		}
+	}
+	for _, mainProto := range mainProtos {
+		visit(mainProto)
+	}
+	return result
+}
+
+// Returns the generated main files: the last file of FdSet or, if SplitFiles is set, the files it is split into.
+func (renderer *Renderer) mainFiles() []*dpb.FileDescriptorProto {
+	if !renderer.SplitFiles {
+		return []*dpb.FileDescriptorProto{getLast(renderer.FdSet.File)}
+	}
+	if renderer.splitFiles == nil {
+		renderer.splitFiles = splitFileDescriptor(renderer.FdSet, renderer.descriptions)
+	}
+	return renderer.splitFiles
+}
+
+// Returns one FileDescriptorSet per split file. The split file is the last file of its set, the other split files
+// and all dependencies of the main file come before it.
+func (renderer *Renderer) buildSplitFileDescriptorSets() []*dpb.FileDescriptorSet {
+	dependencies := renderer.FdSet.File[:len(renderer.FdSet.File)-1]
+	splitFiles := renderer.mainFiles()
+	fdSets := make([]*dpb.FileDescriptorSet, 0)
+	for _, splitFile := range splitFiles {
+		fdSet := &dpb.FileDescriptorSet{File: append([]*dpb.FileDescriptorProto(nil), dependencies...)}
+		for _, other := range splitFiles {
+			if other != splitFile {
+				fdSet.File = append(fdSet.File, other)
+			}
+		}
+		fdSet.File = append(fdSet.File, splitFile)
+		fdSets = append(fdSets, fdSet)
+	}
+	return fdSets
+}
@@ -15,13 +15,16 @@
 package generator
 
 import (
-	surface "github.com/googleapis/gnostic/surface"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	plugins "github.com/googleapis/gnostic/plugins"
+	prDesc "github.com/jhump/protoreflect/desc"
 	"io/ioutil"
 	"os"
-	"os/exec"
 	"path"
 	"path/filepath"
 	"strings"
+	"sync"
 	"testing"
 )
 
@@ -67,6 +70,517 @@ func TestFileDescriptorGeneratorResponses(t *testing.T) {
 	checkContents(t, string(protoData), "goldstandard/responses.proto")
 }
 
+func TestFileDescriptorGeneratorEnums(t *testing.T) {
+	input := "testfiles/enums.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "enums")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/enums.proto")
+
+	_, err = runGeneratorWithoutEnvironment("testfiles/errors/enums_range.yaml", "enums_range")
+	expected := "the value '3000000000' of the enum Code is not a 32-bit integer"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error: %s, got: %v", expected, err)
+	}
+}
+
+func TestFileDescriptorGeneratorSymbolicEnums(t *testing.T) {
+	// gnostic only treats references to absolute paths and URLs as symbolic references.
+	input, err := filepath.Abs("testfiles/symbolicEnums.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "symbolicenums")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/symbolicenums.proto")
+}
+
+func TestFileDescriptorGeneratorPolymorphism(t *testing.T) {
+	input := "testfiles/polymorphism.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "polymorphism")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/polymorphism.proto")
+}
+
+func TestFileDescriptorGeneratorAllOf(t *testing.T) {
+	input := "testfiles/allOf.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "allof")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/allof.proto")
+
+	_, err = runGeneratorWithoutEnvironment("testfiles/errors/allOf_conflict.yaml", "allof_conflict")
+	expected := "conflicting types for property 'id' inside of allOf of Dog: 'scalar integer (int64)' (Pet) and 'scalar string' (DogAllOf2)"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error: %s, got: %v", expected, err)
+	}
+}
+
+func TestFileDescriptorGeneratorLockfile(t *testing.T) {
+	input := "testfiles/lockfile.yaml"
+
+	lock, err := ReadFieldNumberLock("testfiles/lockfile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	protoData, err := runGeneratorWithRenderer(input, "lockfile", func(r *Renderer) {
+		r.FieldNumberLock = lock
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/lockfile.proto")
+
+	lockFile, err := ioutil.TempFile("", "lockfile")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(lockFile.Name())
+	if err := lock.Write(lockFile.Name()); err != nil {
+		t.Fatal(err)
+	}
+	lockData, err := ioutil.ReadFile(lockFile.Name())
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkContents(t, string(lockData), "goldstandard/lockfile.json")
+
+	lock, err = ReadFieldNumberLock("testfiles/lockfile.json")
+	if err != nil {
+		t.Fatal(err)
+	}
+	lock.Messages["lockfile.Address"].Fields["street"] = 536870911
+	_, err = runGeneratorWithRenderer(input, "lockfile", func(r *Renderer) {
+		r.FieldNumberLock = lock
+	})
+	expected := "no field number left for lockfile.Address.city"
+	if err == nil || err.Error() != expected {
+		t.Errorf("Expected error: %s, got: %v", expected, err)
+	}
+}
+
+func TestFileDescriptorGeneratorExtensions(t *testing.T) {
+	input := "testfiles/extensions.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "extensions")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/extensions.proto")
+
+	for input, expected := range map[string]string{
+		"testfiles/errors/extensions_name.yaml":      "property 'pages' of Book: x-proto-name: 'page-count' is not a valid field name",
+		"testfiles/errors/extensions_duplicate.yaml": "the properties 'title' and 'subtitle' of Book have the same field name: title",
+		"testfiles/errors/extensions_type.yaml":      "property 'ratings' of Book: x-proto-type: the type of message and map fields cannot be changed",
+	} {
+		_, err = runGeneratorWithoutEnvironment(input, "extensions")
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected error: %s, got: %v", expected, err)
+		}
+	}
+}
+
+func TestRenderDescriptor(t *testing.T) {
+	input := "testfiles/parameters.yaml"
+
+	var renderer *Renderer
+	_, err := runGeneratorWithRenderer(input, "parameters", func(r *Renderer) {
+		renderer = r
+	})
+	if err != nil {
+		handleError(err, t)
+		return
+	}
+
+	for includeImports, expectedFiles := range map[bool][]string{
+		false: {"parameters.proto"},
+		true: {"google/protobuf/descriptor.proto", "google/api/annotations.proto", "google/protobuf/empty.proto",
+			"parameters.proto"},
+	} {
+		renderer.IncludeImports = includeImports
+		f, err := renderer.RenderDescriptor()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if f.Name != "parameters.descr" {
+			t.Errorf("Unexpected file name: %s", f.Name)
+		}
+
+		fdSet := &dpb.FileDescriptorSet{}
+		if err := proto.Unmarshal(f.Data, fdSet); err != nil {
+			t.Fatal(err)
+		}
+		files := make([]string, 0)
+		for _, fd := range fdSet.File {
+			files = append(files, fd.GetName())
+		}
+		if strings.Join(files, ",") != strings.Join(expectedFiles, ",") {
+			t.Errorf("Expected files %v, got %v", expectedFiles, files)
+		}
+		if includeImports {
+			// The set has to be self-contained.
+			if _, err := prDesc.CreateFileDescriptorFromSet(fdSet); err != nil {
+				t.Error(err)
+			}
+		}
+	}
+}
+
+func TestFileDescriptorGeneratorComments(t *testing.T) {
+	input := "testfiles/comments.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "comments")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/comments.proto")
+}
+
+func TestFileDescriptorGeneratorTimeTypes(t *testing.T) {
+	input := "testfiles/timeTypes.yaml"
+
+	protoData, err := runGeneratorWithRenderer(input, "timetypes", func(r *Renderer) {
+		r.TimeTypes = true
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/timetypes.proto")
+
+	// Without the option, the well-known types are neither used nor imported.
+	protoData, err = runGeneratorWithoutEnvironment(input, "timetypes")
+	if err != nil {
+		handleError(err, t)
+	}
+	if strings.Contains(string(protoData), "google/protobuf/timestamp.proto") {
+		t.Errorf("Unexpected import of google/protobuf/timestamp.proto")
+	}
+}
+
+func TestFileDescriptorGeneratorOptionalFields(t *testing.T) {
+	input := "testfiles/optional.yaml"
+
+	protoData, err := runGeneratorWithRenderer(input, "optional", func(r *Renderer) {
+		r.OptionalFields = OptionalFieldsWrappers
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/optional_wrappers.proto")
+
+	for strategy, expected := range map[string]string{
+		"pointers": "unknown strategy for optional fields: 'pointers'",
+		OptionalFieldsProto3: "the strategy 'proto3_optional' for optional fields is not supported: the descriptor " +
+			"library (github.com/golang/protobuf v1.3.2) cannot represent proto3 optional fields, use 'wrappers' instead",
+	} {
+		_, err := runGeneratorWithRenderer(input, "optional", func(r *Renderer) {
+			r.OptionalFields = strategy
+		})
+		if err == nil || err.Error() != expected {
+			t.Errorf("Expected error: %s, got: %v", expected, err)
+		}
+	}
+}
+
+func TestFileDescriptorGeneratorFieldBehavior(t *testing.T) {
+	input := "testfiles/fieldBehavior.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "fieldbehavior")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/fieldbehavior.proto")
+}
+
+func TestFileDescriptorGeneratorValidateRules(t *testing.T) {
+	input := "testfiles/validate.yaml"
+
+	protoData, err := runGeneratorWithRenderer(input, "validaterules", func(r *Renderer) {
+		r.ValidateRules = true
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/validaterules.proto")
+}
+
+func TestFileDescriptorGeneratorMapValues(t *testing.T) {
+	input := "testfiles/mapValues.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "mapvalues")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/mapvalues.proto")
+}
+
+func TestFileDescriptorGeneratorFreeForm(t *testing.T) {
+	input := "testfiles/freeForm.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "freeform")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/freeform.proto")
+}
+
+func TestFileDescriptorGeneratorHttpBody(t *testing.T) {
+	input := "testfiles/httpBody.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "httpbody")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/httpbody.proto")
+}
+
+func TestFileDescriptorGeneratorFormData(t *testing.T) {
+	input := "testfiles/formData.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "formdata")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/formdata.proto")
+}
+
+func TestFileDescriptorGeneratorMetadata(t *testing.T) {
+	input := "testfiles/metadata.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "metadata")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/metadata.proto")
+}
+
+func TestRenderMetadata(t *testing.T) {
+	input := "testfiles/metadata.yaml"
+
+	documentv3, err := readOpenAPIDocument(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaceModel, err := buildSurfaceModel(documentv3, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The Go helper is only rendered on request. The definition of the option is a dependency and never rendered.
+	for _, metadataHelper := range []bool{false, true} {
+		renderer := NewRenderer(surfaceModel)
+		renderer.Package = "metadata"
+		renderer.Document = documentv3
+		renderer.MetadataHelper = metadataHelper
+
+		response := &plugins.Response{}
+		if err := renderer.Render(response, "metadata.proto"); err != nil {
+			handleError(err, t)
+			return
+		}
+		files := make(map[string]string)
+		names := make([]string, 0)
+		for _, f := range response.Files {
+			files[f.Name] = string(f.Data)
+			names = append(names, f.Name)
+		}
+		expectedNames := []string{"metadata.proto"}
+		if metadataHelper {
+			expectedNames = append(expectedNames, "metadata_metadata.go")
+			checkContents(t, files["metadata_metadata.go"], "goldstandard/metadata_metadata.go.txt")
+		}
+		if strings.Join(names, ",") != strings.Join(expectedNames, ",") {
+			t.Errorf("Expected files %v, got %v", expectedNames, names)
+		}
+	}
+}
+
+func TestFileDescriptorGeneratorServicePerTag(t *testing.T) {
+	input := "testfiles/tags.yaml"
+
+	protoData, err := runGeneratorWithRenderer(input, "tags", func(r *Renderer) {
+		r.ServicePerTag = true
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/tags.proto")
+}
+
+func TestFileDescriptorGeneratorNaming(t *testing.T) {
+	input := "testfiles/naming.yaml"
+
+	protoData, err := runGeneratorWithoutEnvironment(input, "naming")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/naming.proto")
+
+	protoData, err = runGeneratorWithoutEnvironment("testfiles/namingDuplicate.yaml", "namingduplicate")
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/namingduplicate.proto")
+}
+
+func TestFileDescriptorGeneratorFileOptions(t *testing.T) {
+	input := "testfiles/fileOptions.yaml"
+
+	// The plugin parameters override the options of the OpenAPI description.
+	protoData, err := runGeneratorWithRenderer(input, "fileoptions", func(r *Renderer) {
+		r.FileOptions = &dpb.FileOptions{
+			GoPackage:       proto.String("github.com/acme/petstore/v2;petstorepb"),
+			ObjcClassPrefix: proto.String("APS"),
+			PhpNamespace:    proto.String("Acme\\Petstore"),
+		}
+	})
+	if err != nil {
+		handleError(err, t)
+	}
+	checkContents(t, string(protoData), "goldstandard/fileoptions.proto")
+}
+
+func TestRenderSplitFiles(t *testing.T) {
+	input := "testfiles/splitFiles.yaml"
+
+	documentv3, err := readOpenAPIDocument(input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	surfaceModel, err := buildSurfaceModel(documentv3, input)
+	if err != nil {
+		t.Fatal(err)
+	}
+	renderer := NewRenderer(surfaceModel)
+	renderer.Package = "splitfiles"
+	renderer.Document = documentv3
+	renderer.ServicePerTag = true
+	renderer.TimeTypes = true
+	renderer.SplitFiles = true
+	renderer.DescriptorSetOut = "splitfiles.descr"
+
+	response := &plugins.Response{}
+	if err := renderer.Render(response, "splitfiles.proto"); err != nil {
+		handleError(err, t)
+		return
+	}
+	files := make(map[string]string)
+	names := make([]string, 0)
+	for _, f := range response.Files {
+		files[f.Name] = string(f.Data)
+		names = append(names, f.Name)
+	}
+	expectedNames := []string{"splitfiles.descr", "splitfiles_types.proto", "splitfiles_pet_service.proto",
+		"splitfiles_store_service.proto"}
+	if strings.Join(names, ",") != strings.Join(expectedNames, ",") {
+		t.Errorf("Expected files %v, got %v", expectedNames, names)
+	}
+	for _, name := range expectedNames[1:] {
+		checkContents(t, files[name], "goldstandard/"+name)
+	}
+
+	// The descriptor set contains the split files instead of the main file.
+	fdSet := &dpb.FileDescriptorSet{}
+	if err := proto.Unmarshal([]byte(files["splitfiles.descr"]), fdSet); err != nil {
+		t.Fatal(err)
+	}
+	descriptorNames := make([]string, 0)
+	for _, fd := range fdSet.File {
+		descriptorNames = append(descriptorNames, fd.GetName())
+	}
+	if strings.Join(descriptorNames, ",") != strings.Join(expectedNames[1:], ",") {
+		t.Errorf("Expected descriptor set with %v, got %v", expectedNames[1:], descriptorNames)
+	}
+}
+
+func TestFileDescriptorGeneratorConcurrency(t *testing.T) {
+	inputs := map[string]string{
+		"parameters":    "testfiles/parameters.yaml",
+		"requestbodies": "testfiles/requestBodies.yaml",
+		"responses":     "testfiles/responses.yaml",
+		"enums":         "testfiles/enums.yaml",
+		"polymorphism":  "testfiles/polymorphism.yaml",
+	}
+
+	// Generate everything in isolation first.
+	expected := make(map[string]string)
+	for packageName, input := range inputs {
+		protoData, err := runGeneratorWithoutEnvironment(input, packageName)
+		if err != nil {
+			handleError(err, t)
+			return
+		}
+		expected[packageName] = string(protoData)
+	}
+
+	// Generating the same documents in parallel has to lead to the same results.
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		for packageName, input := range inputs {
+			wg.Add(1)
+			go func(packageName string, input string) {
+				defer wg.Done()
+				protoData, err := runGeneratorWithoutEnvironment(input, packageName)
+				if err != nil {
+					t.Error(err)
+					return
+				}
+				if string(protoData) != expected[packageName] {
+					t.Errorf("Output for %s differs from isolated run", input)
+				}
+			}(packageName, input)
+		}
+	}
+	wg.Wait()
+}
+
+func TestFileDescriptorGeneratorRepeatedRuns(t *testing.T) {
+	inputs := map[string]string{
+		"naming":   "testfiles/naming.yaml",
+		"httpbody": "testfiles/httpBody.yaml",
+	}
+
+	for packageName, input := range inputs {
+		documentv3, err := readOpenAPIDocument(input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		surfaceModel, err := buildSurfaceModel(documentv3, input)
+		if err != nil {
+			t.Fatal(err)
+		}
+		r := NewRenderer(surfaceModel)
+		r.Package = packageName
+		r.Document = documentv3
+
+		// The second run must neither differ from the first one nor change the descriptors of the first one.
+		fdSets := make([]*dpb.FileDescriptorSet, 0)
+		for i := 0; i < 2; i++ {
+			fdSet, err := r.runFileDescriptorSetGenerator()
+			if err != nil {
+				t.Fatal(err)
+			}
+			fdSets = append(fdSets, fdSet)
+		}
+		for _, fdSet := range fdSets {
+			f, err := r.RenderProto(fdSet, "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			checkContents(t, string(f.Data), "goldstandard/"+packageName+".proto")
+		}
+	}
+}
+
 func TestFileDescriptorGeneratorOther(t *testing.T) {
 	// It could happen that this tests fails, because the imports get rendered in a different order.
 	// Just execute it again.
@@ -93,12 +607,23 @@ func TestFileDescriptorGeneratorOther(t *testing.T) {
 }
 
 func runGeneratorWithoutEnvironment(input string, packageName string) ([]byte, error) {
-	surfaceModel, err := buildSurfaceModel(input)
+	return runGeneratorWithRenderer(input, packageName, func(r *Renderer) {})
+}
+
+// Like runGeneratorWithoutEnvironment, but 'configure' is called with the renderer before the generator runs.
+func runGeneratorWithRenderer(input string, packageName string, configure func(r *Renderer)) ([]byte, error) {
+	documentv3, err := readOpenAPIDocument(input)
+	if err != nil {
+		return nil, err
+	}
+	surfaceModel, err := buildSurfaceModel(documentv3, input)
 	if err != nil {
 		return nil, err
 	}
 	r := NewRenderer(surfaceModel)
 	r.Package = packageName
+	r.Document = documentv3
+	configure(r)
 
 	fdSet, err := r.runFileDescriptorSetGenerator()
 	r.FdSet = fdSet
@@ -112,14 +637,6 @@ func runGeneratorWithoutEnvironment(input string, packageName string) ([]byte, e
 	return f.Data, err
 }
 
-func buildSurfaceModel(input string) (*surface.Model, error) {
-	cmd := exec.Command("gnostic", "--pb-out=-", input)
-	b, _ := cmd.Output()
-	documentv3, _ := createOpenAPIDocFromGnosticOutput(b)
-	surfaceModel, err := surface.NewModelFromOpenAPI3(documentv3, input)
-	return surfaceModel, err
-}
-
 func writeFile(output string, protoData []byte) {
 	dir := path.Dir(output)
 	os.MkdirAll(dir, 0755)
@@ -0,0 +1,659 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+	surface_v1 "github.com/googleapis/gnostic/surface"
+	"gopkg.in/yaml.v2"
+)
+
+// Vendor extensions that steer the generated descriptors from inside of the OpenAPI description. 'x-proto-name' can be
+// set on schemas from the components section (name of the message or enum) and on properties (name of the field).
+// 'x-proto-type' (a protobuf scalar type) and 'x-proto-field-number' can be set on properties. 'x-proto-options' can
+// be set on 'info' (an object with file options, e.g.: 'go_package').
+const (
+	extensionName        = "x-proto-name"
+	extensionType        = "x-proto-type"
+	extensionFieldNumber = "x-proto-field-number"
+	extensionFileOptions = "x-proto-options"
+)
+
+// schemaIndex connects the surface model back to the OpenAPI document it was built from. The surface model drops a
+// lot of information (enums, descriptions, ...) which we need to render a proper .proto. The index uses the same
+// naming scheme as the surface model, so the original schemas can be looked up by the names of surface model types
+// and fields.
+type schemaIndex struct {
+	// Schemas from #/components/schemas by name.
+	components map[string]*openapiv3.Schema
+	// Object schemas by the name of the surface model type that was built from them.
+	types map[string]*openapiv3.Schema
+	// Inline schemas of fields: surface model type name -> field name -> schema. References are not stored here,
+	// they are represented as references to other types inside of the surface model.
+	fields map[string]map[string]*openapiv3.Schema
+	// Operations by the name of the surface model method.
+	operations map[string]*openapiv3.Operation
+	// Descriptions of fields that are not part of their schema (parameters and request bodies): surface model type
+	// name -> field name -> description.
+	descriptions map[string]map[string]string
+	// Required parameters and request bodies: surface model type name -> field name. Required properties are part of
+	// the schema.
+	requiredFields map[string]map[string]bool
+	// Surface model types that are represented by a well-known type instead of a generated message: surface model
+	// type name -> well-known type (e.g.: free-form objects or binary bodies).
+	replacedTypes map[string]string
+	// Descriptions of the tags of the document by name.
+	tags map[string]string
+	// Locations ('path', 'query', 'header' or 'cookie') of parameters: surface model type name -> field name.
+	parameterLocations map[string]map[string]string
+	// Request bodies that only consist of a form: surface model type name of the body -> type name of the form.
+	formBodies map[string]string
+	// Names of the generated messages and enums by the name of the surface model type.
+	protoTypeNames map[string]string
+	// Fields that the surface model creates for the subschemas of 'anyOf', 'oneOf' and 'allOf': surface model type
+	// name -> field name -> 'any_of', 'one_of' or 'all_of'.
+	subSchemaFields map[string]map[string]string
+}
+
+// Creates the index for 'document'. 'document' might be nil, in that case all lookups return nil.
+func newSchemaIndex(document *openapiv3.Document, model *surface_v1.Model) *schemaIndex {
+	idx := &schemaIndex{
+		components:     make(map[string]*openapiv3.Schema),
+		types:          make(map[string]*openapiv3.Schema),
+		fields:         make(map[string]map[string]*openapiv3.Schema),
+		operations:     make(map[string]*openapiv3.Operation),
+		descriptions:   make(map[string]map[string]string),
+		requiredFields: make(map[string]map[string]bool),
+		replacedTypes:  make(map[string]string),
+		formBodies:     make(map[string]string),
+		tags:           make(map[string]string),
+		protoTypeNames: make(map[string]string),
+
+		parameterLocations: make(map[string]map[string]string),
+		subSchemaFields:    make(map[string]map[string]string),
+	}
+	if document != nil {
+		for _, tag := range document.Tags {
+			idx.tags[tag.Name] = tag.Description
+		}
+		idx.indexComponents(document.Components)
+		if model != nil {
+			for _, method := range model.Methods {
+				if op := findOperation(document, method.Path, method.Method); op != nil {
+					idx.indexOperation(method, op)
+				}
+			}
+		}
+	}
+	if model != nil {
+		idx.indexProtoTypeNames(model.Types)
+		idx.indexFreeFormTypes(model.Types)
+		if document != nil {
+			idx.indexBinaryBodies(document, model)
+			idx.indexFormBodies(document, model)
+		}
+	}
+	return idx
+}
+
+// The surface model creates types without fields for schemas that allow arbitrary properties ('type: object' without
+// properties or with 'additionalProperties: true') and for schemas without any type. Free-form objects are
+// represented by google.protobuf.Struct, everything else by google.protobuf.Value. Types without fields whose schema
+// declares properties, additional properties or subschemas (e.g.: 'additionalProperties: false') are not free-form
+// and stay empty messages.
+func (idx *schemaIndex) indexFreeFormTypes(types []*surface_v1.Type) {
+	for _, t := range types {
+		if t.Kind != surface_v1.TypeKind_OBJECT {
+			continue
+		}
+		schema, ok := idx.types[t.Name]
+		if ok && !isFreeFormSchema(schema) {
+			continue
+		}
+		if ok && schema.Type == "" {
+			idx.replacedTypes[t.Name] = "google.protobuf.Value"
+		} else {
+			idx.replacedTypes[t.Name] = "google.protobuf.Struct"
+		}
+	}
+}
+
+// Indexes all schemas, parameters, responses and request bodies of the components section.
+func (idx *schemaIndex) indexComponents(components *openapiv3.Components) {
+	if components == nil {
+		return
+	}
+	if schemas := components.Schemas; schemas != nil {
+		for _, pair := range schemas.AdditionalProperties {
+			if schema := pair.Value.GetSchema(); schema != nil {
+				idx.components[pair.Name] = schema
+				if !isObjectSchema(schema) {
+					// The surface model wraps scalars and arrays inside of a type with a single field: 'value'.
+					idx.addField(pair.Name, "value", schema)
+				}
+			}
+			idx.indexSchema(pair.Name, pair.Value)
+		}
+	}
+	if parameters := components.Parameters; parameters != nil {
+		for _, pair := range parameters.AdditionalProperties {
+			if parameter := pair.Value.GetParameter(); parameter != nil {
+				idx.indexParameter(pair.Name, parameter)
+			}
+		}
+	}
+	if responses := components.Responses; responses != nil {
+		for _, pair := range responses.AdditionalProperties {
+			if response := pair.Value.GetResponse(); response != nil {
+				idx.indexContent(pair.Name, response.Content)
+			}
+		}
+	}
+	if requestBodies := components.RequestBodies; requestBodies != nil {
+		for _, pair := range requestBodies.AdditionalProperties {
+			if requestBody := pair.Value.GetRequestBody(); requestBody != nil {
+				idx.indexContent(pair.Name, requestBody.Content)
+			}
+		}
+	}
+}
+
+// Indexes the parameters, the request body and the responses of 'op'.
+func (idx *schemaIndex) indexOperation(method *surface_v1.Method, op *openapiv3.Operation) {
+	idx.operations[method.Name] = op
+
+	for _, paramOrRef := range op.Parameters {
+		if parameter := paramOrRef.GetParameter(); parameter != nil {
+			idx.indexParameter(method.ParametersTypeName, parameter)
+		}
+	}
+	if requestBody := op.RequestBody.GetRequestBody(); requestBody != nil {
+		idx.addDescription(method.ParametersTypeName, "request_body", requestBody.Description)
+		if requestBody.Required {
+			idx.addRequiredField(method.ParametersTypeName, "request_body")
+		}
+		idx.indexContent(op.OperationId+"RequestBody", requestBody.Content)
+	}
+	if responses := op.Responses; responses != nil {
+		for _, pair := range responses.ResponseOrReference {
+			if response := pair.Value.GetResponse(); response != nil {
+				idx.indexContent(op.OperationId+convertStatusCodes(pair.Name), response.Content)
+			}
+		}
+		if response := responses.Default.GetResponse(); response != nil {
+			idx.indexContent(op.OperationId+"Default", response.Content)
+		}
+	}
+}
+
+// Parameters are fields of the type 'typeName'. The name of the field is the name of the parameter.
+func (idx *schemaIndex) indexParameter(typeName string, parameter *openapiv3.Parameter) {
+	idx.addDescription(typeName, parameter.Name, parameter.Description)
+	if _, ok := idx.parameterLocations[typeName]; !ok {
+		idx.parameterLocations[typeName] = make(map[string]string)
+	}
+	idx.parameterLocations[typeName][parameter.Name] = parameter.In
+	if parameter.Required {
+		idx.addRequiredField(typeName, parameter.Name)
+	}
+	if schema := parameter.Schema.GetSchema(); schema != nil {
+		idx.addField(typeName, parameter.Name, schema)
+	}
+	idx.indexSchema(parameter.Name, parameter.Schema)
+}
+
+// Responses and request bodies are types with one field per media type.
+func (idx *schemaIndex) indexContent(typeName string, content *openapiv3.MediaTypes) {
+	if content == nil {
+		return
+	}
+	for _, pair := range content.AdditionalProperties {
+		if schema := pair.Value.Schema.GetSchema(); schema != nil {
+			idx.addField(typeName, pair.Name, schema)
+		}
+		idx.indexSchema(typeName+pair.Name, pair.Value.Schema)
+	}
+}
+
+// Recursively indexes an inline schema. The names match the names the surface model uses for the types it creates.
+func (idx *schemaIndex) indexSchema(name string, schemaOrReference *openapiv3.SchemaOrReference) {
+	schema := schemaOrReference.GetSchema()
+	if schema == nil {
+		return
+	}
+
+	switch schema.Type {
+	case "", "object":
+		idx.types[name] = schema
+		if schema.Properties != nil {
+			for _, pair := range schema.Properties.AdditionalProperties {
+				if s := pair.Value.GetSchema(); s != nil {
+					idx.addField(name, pair.Name, s)
+				}
+				idx.indexSchema(pair.Name, pair.Value)
+			}
+		}
+		if schemaOrRef := schema.AdditionalProperties.GetSchemaOrReference(); schemaOrRef != nil {
+			if s := schemaOrRef.GetSchema(); s != nil {
+				idx.addField(name, "additional_properties", s)
+			}
+			idx.indexSchema(name+"AdditionalProperties", schemaOrRef)
+		}
+		idx.indexSubSchemas(name, "AnyOf", "any_of_", schema.AnyOf)
+		idx.indexSubSchemas(name, "OneOf", "one_of_", schema.OneOf)
+		idx.indexSubSchemas(name, "AllOf", "all_of_", schema.AllOf)
+		if schema.Items != nil {
+			for _, schemaOrRef := range schema.Items.SchemaOrReference {
+				idx.indexSchema(name+"Items", schemaOrRef)
+			}
+		}
+	case "array":
+		if schema.Items != nil {
+			for _, schemaOrRef := range schema.Items.SchemaOrReference {
+				idx.indexSchema(name, schemaOrRef)
+			}
+		}
+	}
+}
+
+// Indexes the schemas of 'anyOf', 'oneOf' and 'allOf'.
+func (idx *schemaIndex) indexSubSchemas(name string, typeSuffix string, fieldPrefix string, schemas []*openapiv3.SchemaOrReference) {
+	for i, schemaOrRef := range schemas {
+		n := strconv.Itoa(i + 1)
+		if _, ok := idx.subSchemaFields[name]; !ok {
+			idx.subSchemaFields[name] = make(map[string]string)
+		}
+		idx.subSchemaFields[name][fieldPrefix+n] = strings.TrimSuffix(fieldPrefix, "_")
+		if s := schemaOrRef.GetSchema(); s != nil {
+			idx.addField(name, fieldPrefix+n, s)
+		}
+		idx.indexSchema(name+typeSuffix+n, schemaOrRef)
+	}
+}
+
+func (idx *schemaIndex) addField(typeName string, fieldName string, schema *openapiv3.Schema) {
+	if _, ok := idx.fields[typeName]; !ok {
+		idx.fields[typeName] = make(map[string]*openapiv3.Schema)
+	}
+	idx.fields[typeName][fieldName] = schema
+}
+
+func (idx *schemaIndex) addDescription(typeName string, fieldName string, description string) {
+	if description == "" {
+		return
+	}
+	if _, ok := idx.descriptions[typeName]; !ok {
+		idx.descriptions[typeName] = make(map[string]string)
+	}
+	idx.descriptions[typeName][fieldName] = description
+}
+
+// Request bodies and responses that only consist of binary media types are represented by google.api.HttpBody, so
+// that they are transcoded as raw bytes together with their content type. If the binary body is the only field of the
+// parameters or responses of a method, the method uses google.api.HttpBody directly.
+func (idx *schemaIndex) indexBinaryBodies(document *openapiv3.Document, model *surface_v1.Model) {
+	types := make(map[string]*surface_v1.Type)
+	for _, t := range model.Types {
+		types[t.Name] = t
+	}
+
+	for _, method := range model.Methods {
+		op := findOperation(document, method.Path, method.Method)
+		if op == nil {
+			continue
+		}
+		if requestBody := op.RequestBody.GetRequestBody(); requestBody != nil && isBinaryContent(requestBody.Content) {
+			idx.replaceBinaryBody(types, method.ParametersTypeName, op.OperationId+"RequestBody")
+		}
+		if responses := op.Responses; responses != nil {
+			for _, pair := range responses.ResponseOrReference {
+				if response := pair.Value.GetResponse(); response != nil && isBinaryContent(response.Content) {
+					idx.replaceBinaryBody(types, method.ResponsesTypeName, op.OperationId+convertStatusCodes(pair.Name))
+				}
+			}
+			if response := responses.Default.GetResponse(); response != nil && isBinaryContent(response.Content) {
+				idx.replaceBinaryBody(types, method.ResponsesTypeName, op.OperationId+"Default")
+			}
+		}
+	}
+}
+
+// Replaces the type 'bodyTypeName' by google.api.HttpBody. 'ownerTypeName' is replaced as well, if the body is its
+// only field.
+func (idx *schemaIndex) replaceBinaryBody(types map[string]*surface_v1.Type, ownerTypeName string, bodyTypeName string) {
+	idx.replacedTypes[bodyTypeName] = "google.api.HttpBody"
+	if owner, ok := types[ownerTypeName]; ok && len(owner.Fields) == 1 && owner.Fields[0].Type == bodyTypeName {
+		idx.replacedTypes[ownerTypeName] = "google.api.HttpBody"
+	}
+}
+
+// Request bodies that only have a single form media type ('multipart/form-data' or
+// 'application/x-www-form-urlencoded') are represented by the message of the form, so that every field of the request
+// body is a form part.
+func (idx *schemaIndex) indexFormBodies(document *openapiv3.Document, model *surface_v1.Model) {
+	types := make(map[string]*surface_v1.Type)
+	for _, t := range model.Types {
+		types[t.Name] = t
+	}
+
+	for _, method := range model.Methods {
+		op := findOperation(document, method.Path, method.Method)
+		requestBody := op.GetRequestBody().GetRequestBody()
+		if requestBody == nil || requestBody.Content == nil || len(requestBody.Content.AdditionalProperties) != 1 ||
+			!isFormMediaType(requestBody.Content.AdditionalProperties[0].Name) {
+			continue
+		}
+		bodyTypeName := op.OperationId + "RequestBody"
+		if body, ok := types[bodyTypeName]; ok && len(body.Fields) == 1 && body.Fields[0].Kind == surface_v1.FieldKind_REFERENCE {
+			formTypeName := body.Fields[0].Type
+			idx.formBodies[bodyTypeName] = formTypeName
+			// The message of an inline form takes the name of the request body it replaces (e.g.: 'UploadPhotoRequestBody'
+			// instead of 'UploadPhotoRequestBodymultipartFormData'). Forms from the components section keep their name.
+			if _, ok := idx.components[formTypeName]; !ok {
+				idx.protoTypeNames[formTypeName] = idx.protoTypeName(bodyTypeName)
+			}
+		}
+	}
+}
+
+// Returns the type of the form the request body 'typeName' consists of or false if it is not a form.
+func (idx *schemaIndex) formBodyType(typeName string) (string, bool) {
+	formTypeName, ok := idx.formBodies[typeName]
+	return formTypeName, ok
+}
+
+// Returns true if the media type 'mediaType' is a form.
+func isFormMediaType(mediaType string) bool {
+	mediaType = strings.ToLower(strings.TrimSpace(strings.Split(mediaType, ";")[0]))
+	return mediaType == "multipart/form-data" || mediaType == "application/x-www-form-urlencoded"
+}
+
+// Returns true if 'content' only has binary media types (e.g.: 'application/octet-stream', 'image/png' or a schema
+// with the format 'binary').
+func isBinaryContent(content *openapiv3.MediaTypes) bool {
+	if content == nil || len(content.AdditionalProperties) == 0 {
+		return false
+	}
+	for _, pair := range content.AdditionalProperties {
+		schema := pair.Value.Schema.GetSchema()
+		if schema != nil && schema.Type == "string" && schema.Format == "binary" {
+			continue
+		}
+		mediaType := strings.ToLower(pair.Name)
+		if mediaType != "application/octet-stream" && !strings.HasPrefix(mediaType, "image/") &&
+			!strings.HasPrefix(mediaType, "audio/") && !strings.HasPrefix(mediaType, "video/") {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the first tag of the operation of the surface model method 'methodName' or false if it has none.
+func (idx *schemaIndex) operationTag(methodName string) (string, bool) {
+	if op, ok := idx.operations[methodName]; ok && len(op.Tags) > 0 {
+		return op.Tags[0], true
+	}
+	return "", false
+}
+
+// Returns the description of the tag 'tag'.
+func (idx *schemaIndex) tagDescription(tag string) string {
+	return idx.tags[tag]
+}
+
+// Returns the description of the schema the surface model type 'typeName' was built from.
+func (idx *schemaIndex) typeDescription(typeName string) string {
+	if schema, ok := idx.types[typeName]; ok {
+		return schema.GetDescription()
+	}
+	return idx.components[typeName].GetDescription()
+}
+
+// Returns the location of the parameter 'fieldName' of the type 'typeName' (e.g.: 'header') or an empty string if it
+// is not a parameter. The surface model does not know about cookie parameters.
+func (idx *schemaIndex) parameterLocation(typeName string, fieldName string) string {
+	return idx.parameterLocations[typeName][fieldName]
+}
+
+// Returns the description of the field 'fieldName' of the type 'typeName'. The description of a parameter takes
+// precedence over the description of its schema.
+func (idx *schemaIndex) fieldDescription(typeName string, fieldName string) string {
+	if description, ok := idx.descriptions[typeName][fieldName]; ok {
+		return description
+	}
+	return idx.fieldSchema(typeName, fieldName).GetDescription()
+}
+
+func (idx *schemaIndex) addRequiredField(typeName string, fieldName string) {
+	if _, ok := idx.requiredFields[typeName]; !ok {
+		idx.requiredFields[typeName] = make(map[string]bool)
+	}
+	idx.requiredFields[typeName][fieldName] = true
+}
+
+// Returns true if the field 'fieldName' of the type 'typeName' is a required parameter, request body or property.
+func (idx *schemaIndex) isRequiredField(typeName string, fieldName string) bool {
+	if idx.requiredFields[typeName][fieldName] {
+		return true
+	}
+	for _, name := range idx.types[typeName].GetRequired() {
+		if name == fieldName {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the field 'fieldName' of the type 'typeName' can be absent: it is either nullable or neither a
+// required parameter nor a required property. The 'value' of scalar schemas from the components section is only
+// optional if it is nullable.
+func (idx *schemaIndex) isOptionalField(typeName string, fieldName string) bool {
+	if idx.fieldSchema(typeName, fieldName).GetNullable() {
+		return true
+	}
+	if schema, ok := idx.components[typeName]; ok && !isObjectSchema(schema) {
+		return false
+	}
+	return !idx.isRequiredField(typeName, fieldName)
+}
+
+// Returns the summary and the description of the operation of the surface model method 'methodName'.
+func (idx *schemaIndex) methodDescription(methodName string) string {
+	op := idx.operations[methodName]
+	if op == nil {
+		return ""
+	}
+	parts := make([]string, 0)
+	for _, part := range []string{op.Summary, op.Description} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, "\n\n")
+}
+
+// Returns 'any_of', 'one_of' or 'all_of' if the field 'fieldName' of the type 'typeName' was created for a subschema
+// of 'anyOf', 'oneOf' or 'allOf' or false if it is a regular field (e.g.: a property).
+func (idx *schemaIndex) subSchemaGroup(typeName string, fieldName string) (string, bool) {
+	group, ok := idx.subSchemaFields[typeName][fieldName]
+	return group, ok
+}
+
+// Returns the inline schema of the field 'fieldName' of the type 'typeName' or nil.
+func (idx *schemaIndex) fieldSchema(typeName string, fieldName string) *openapiv3.Schema {
+	return idx.fields[typeName][fieldName]
+}
+
+// Returns the well-known type that represents the surface model type 'typeName' or false if a message is generated
+// for it.
+func (idx *schemaIndex) replacedType(typeName string) (string, bool) {
+	typeName, ok := idx.replacedTypes[typeName]
+	return typeName, ok
+}
+
+// Returns the name of the message or enum that is generated for the surface model type 'typeName'. Schemas from the
+// components section can override the name with 'x-proto-name'.
+func (idx *schemaIndex) protoTypeName(typeName string) string {
+	if name, ok := idx.protoTypeNames[typeName]; ok {
+		return name
+	}
+	extensions := idx.components[typeName].GetSpecificationExtension()
+	if name, ok := getExtension(extensions, extensionName); ok {
+		return name
+	}
+	return cleanTypeName(typeName)
+}
+
+// Assigns unique names to the messages and enums of 'types'. Different surface model types can get the same name
+// after cleaning (e.g.: 'list_petsOK' and 'ListPetsOK' for the operationIds 'list_pets' and 'ListPets'). Names that
+// are set with 'x-proto-name' are kept, all other names get a number (starting with 2) in the order of the types.
+func (idx *schemaIndex) indexProtoTypeNames(types []*surface_v1.Type) {
+	used := make(map[string]bool)
+	for _, t := range types {
+		if name, ok := getExtension(idx.components[t.Name].GetSpecificationExtension(), extensionName); ok {
+			idx.protoTypeNames[t.Name] = name
+			used[name] = true
+		}
+	}
+	for _, t := range types {
+		if _, ok := idx.protoTypeNames[t.Name]; ok {
+			continue
+		}
+		name := cleanTypeName(t.Name)
+		unique := name
+		for i := 2; used[unique]; i++ {
+			unique = name + strconv.Itoa(i)
+		}
+		used[unique] = true
+		idx.protoTypeNames[t.Name] = unique
+	}
+}
+
+// Returns true if 'typeName' is a schema from the components section that is generated as enum.
+func (idx *schemaIndex) isEnumType(typeName string) bool {
+	return isEnumSchema(idx.components[typeName])
+}
+
+// Returns the schema of the inline enum of the field 'fieldName' or nil. Arrays of enums are also considered.
+func (idx *schemaIndex) enumForField(typeName string, fieldName string) *openapiv3.Schema {
+	schema := idx.fieldSchema(typeName, fieldName)
+	if schema != nil && schema.Type == "array" && schema.Items != nil && len(schema.Items.SchemaOrReference) > 0 {
+		schema = schema.Items.SchemaOrReference[0].GetSchema()
+	}
+	if isEnumSchema(schema) {
+		return schema
+	}
+	return nil
+}
+
+// Only string and integer enums are generated as enums inside of .proto.
+func isEnumSchema(schema *openapiv3.Schema) bool {
+	return schema != nil && len(schema.Enum) > 0 && (schema.Type == "string" || schema.Type == "integer")
+}
+
+// The surface model creates a type for every schema that is an object or has no type at all.
+func isObjectSchema(schema *openapiv3.Schema) bool {
+	return schema.Type == "" || schema.Type == "object"
+}
+
+// Returns true if 'schema' allows arbitrary values: it declares neither properties, nor additional properties other
+// than 'additionalProperties: true', nor subschemas ('allOf', 'oneOf' or 'anyOf').
+func isFreeFormSchema(schema *openapiv3.Schema) bool {
+	if len(schema.GetProperties().GetAdditionalProperties()) > 0 {
+		return false
+	}
+	if additionalProperties := schema.AdditionalProperties; additionalProperties != nil &&
+		(additionalProperties.GetSchemaOrReference() != nil || !additionalProperties.GetBoolean()) {
+		return false
+	}
+	return len(schema.AllOf) == 0 && len(schema.OneOf) == 0 && len(schema.AnyOf) == 0
+}
+
+// Returns the operation for the HTTP 'method' on 'path' or nil.
+func findOperation(document *openapiv3.Document, path string, method string) *openapiv3.Operation {
+	if document.Paths == nil {
+		return nil
+	}
+	for _, pair := range document.Paths.Path {
+		if pair.Name != path || pair.Value == nil {
+			continue
+		}
+		switch method {
+		case "GET":
+			return pair.Value.Get
+		case "PUT":
+			return pair.Value.Put
+		case "POST":
+			return pair.Value.Post
+		case "DELETE":
+			return pair.Value.Delete
+		case "PATCH":
+			return pair.Value.Patch
+		}
+	}
+	return nil
+}
+
+// Returns the value of the vendor extension 'name' or false if it is not set.
+func getExtension(extensions []*openapiv3.NamedAny, name string) (string, bool) {
+	for _, extension := range extensions {
+		if extension.Name == name {
+			return stringValueOfAny(extension.Value)
+		}
+	}
+	return "", false
+}
+
+// Returns the string representation of an enum value or the value of a vendor extension. Returns false for null values.
+func stringValueOfAny(value *openapiv3.Any) (string, bool) {
+	if value == nil {
+		return "", false
+	}
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value.Yaml), &v); err != nil || v == nil {
+		return "", false
+	}
+	return fmt.Sprint(v), true
+}
+
+// Converts 'name' to lower snake case: 'photoUrls' becomes 'photo_urls', 'HTTPServer' becomes 'http_server'.
+// All characters that are not allowed inside of a .proto identifier are treated as word boundaries.
+func toSnakeCase(name string) string {
+	runes := []rune(name)
+	var b strings.Builder
+	for i, r := range runes {
+		switch {
+		case r > unicode.MaxASCII:
+			b.WriteRune('_')
+		case unicode.IsUpper(r):
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				b.WriteRune('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+		case unicode.IsLetter(r) || unicode.IsDigit(r):
+			b.WriteRune(r)
+		default:
+			b.WriteRune('_')
+		}
+	}
+	words := strings.FieldsFunc(b.String(), func(r rune) bool { return r == '_' })
+	return strings.Join(words, "_")
+}
@@ -0,0 +1,262 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"strings"
+
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/googleapis/gnostic-grpc/metadata"
+	"google.golang.org/genproto/googleapis/api/annotations"
+)
+
+// Splits the main file of 'fdSet' (its last file) into several files of the same package:
+//
+//	<package>_types.proto      messages and enums that are used by several services or by none
+//	<package>_<service>.proto  one file per service with the messages and enums that only this service uses
+//
+// A message that is referenced by a message of the types file is always part of the types file, so the types file
+// never imports the file of a service. The imports of every file are computed from the types and options it actually
+// uses. 'descriptions' are rendered as comments.
+func splitFileDescriptor(fdSet *dpb.FileDescriptorSet, descriptions map[proto.Message]string) []*dpb.FileDescriptorProto {
+	fd := getLast(fdSet.File)
+	baseName := strings.TrimSuffix(fd.GetName(), ".proto")
+	split := &fileSplitter{
+		fd:         fd,
+		messages:   make(map[string]*dpb.DescriptorProto),
+		enums:      make(map[string]*dpb.EnumDescriptorProto),
+		references: make(map[string][]string),
+	}
+	for _, message := range fd.MessageType {
+		split.messages[message.GetName()] = message
+	}
+	for _, enum := range fd.EnumType {
+		split.enums[enum.GetName()] = enum
+	}
+	for _, message := range fd.MessageType {
+		split.references[message.GetName()] = split.referencedTypes(message)
+	}
+
+	// The services that use a type (directly or through the fields of other messages).
+	owners := make(map[string][]int)
+	for i, service := range fd.Service {
+		for name := range split.reachableTypes(service) {
+			owners[name] = append(owners[name], i)
+		}
+	}
+	shared := make(map[string]bool)
+	var share func(name string)
+	share = func(name string) {
+		if shared[name] {
+			return
+		}
+		shared[name] = true
+		for _, reference := range split.references[name] {
+			share(reference)
+		}
+	}
+	for _, name := range split.typeNames() {
+		if len(owners[name]) != 1 {
+			share(name)
+		}
+	}
+
+	newFile := func(name string) *dpb.FileDescriptorProto {
+		return &dpb.FileDescriptorProto{
+			Name:    proto.String(name),
+			Package: fd.Package,
+			Syntax:  fd.Syntax,
+			Options: fd.Options,
+		}
+	}
+	typesFile := newFile(baseName + "_types.proto")
+	serviceFiles := make([]*dpb.FileDescriptorProto, len(fd.Service))
+	for i, service := range fd.Service {
+		serviceFiles[i] = newFile(baseName + "_" + toSnakeCase(service.GetName()) + ".proto")
+		serviceFiles[i].Service = []*dpb.ServiceDescriptorProto{service}
+	}
+	fileOf := func(name string) *dpb.FileDescriptorProto {
+		if shared[name] {
+			return typesFile
+		}
+		return serviceFiles[owners[name][0]]
+	}
+	for _, message := range fd.MessageType {
+		file := fileOf(message.GetName())
+		file.MessageType = append(file.MessageType, message)
+	}
+	for _, enum := range fd.EnumType {
+		file := fileOf(enum.GetName())
+		file.EnumType = append(file.EnumType, enum)
+	}
+
+	files := make([]*dpb.FileDescriptorProto, 0)
+	if len(typesFile.MessageType) > 0 || len(typesFile.EnumType) > 0 {
+		files = append(files, typesFile)
+	}
+	files = append(files, serviceFiles...)
+
+	// Every type of the set (including the ones of the split files) by its fully qualified name.
+	definitions := make(map[string]string)
+	for _, file := range append(append([]*dpb.FileDescriptorProto(nil), fdSet.File...), files...) {
+		if file != fd {
+			addTypeDefinitions(definitions, file)
+		}
+	}
+	for _, file := range files {
+		split.buildDependencies(file, definitions)
+		buildSourceCodeInfo(file, descriptions)
+	}
+	return files
+}
+
+type fileSplitter struct {
+	// The file that is split.
+	fd *dpb.FileDescriptorProto
+	// Top-level messages and enums of 'fd' by their name.
+	messages map[string]*dpb.DescriptorProto
+	enums    map[string]*dpb.EnumDescriptorProto
+	// The top-level types that the fields of a message (and of its nested messages) reference.
+	references map[string][]string
+}
+
+// Returns the names of all top-level types of the split file in the order they are declared.
+func (s *fileSplitter) typeNames() []string {
+	names := make([]string, 0)
+	for _, message := range s.fd.MessageType {
+		names = append(names, message.GetName())
+	}
+	for _, enum := range s.fd.EnumType {
+		names = append(names, enum.GetName())
+	}
+	return names
+}
+
+// Returns the name of the top-level type of the split file that 'typeName' refers to or "" if it refers to a type
+// of another file or to a nested type. 'typeName' is either fully qualified or relative to the package.
+func (s *fileSplitter) topLevelType(typeName string) string {
+	name := strings.TrimPrefix(typeName, ".")
+	name = strings.TrimPrefix(name, s.fd.GetPackage()+".")
+	if i := strings.Index(name, "."); i >= 0 {
+		name = name[:i]
+	}
+	if _, ok := s.messages[name]; ok {
+		return name
+	}
+	if _, ok := s.enums[name]; ok {
+		return name
+	}
+	return ""
+}
+
+// Returns the top-level types that are referenced by the fields of 'message' and its nested messages.
+func (s *fileSplitter) referencedTypes(message *dpb.DescriptorProto) []string {
+	references := make([]string, 0)
+	for _, field := range message.Field {
+		if name := s.topLevelType(field.GetTypeName()); name != "" && name != message.GetName() {
+			references = append(references, name)
+		}
+	}
+	for _, nested := range message.NestedType {
+		references = append(references, s.referencedTypes(nested)...)
+	}
+	return references
+}
+
+// Returns all top-level types that the methods of 'service' use.
+func (s *fileSplitter) reachableTypes(service *dpb.ServiceDescriptorProto) map[string]bool {
+	reachable := make(map[string]bool)
+	var visit func(name string)
+	visit = func(name string) {
+		if name == "" || reachable[name] {
+			return
+		}
+		reachable[name] = true
+		for _, reference := range s.references[name] {
+			visit(reference)
+		}
+	}
+	for _, method := range service.Method {
+		visit(s.topLevelType(method.GetInputType()))
+		visit(s.topLevelType(method.GetOutputType()))
+	}
+	return reachable
+}
+
+// Sets the dependencies of 'file' to the files that define the types and options it uses, in the order of their
+// first usage. 'definitions' maps fully qualified type names to the names of the files that define them.
+func (s *fileSplitter) buildDependencies(file *dpb.FileDescriptorProto, definitions map[string]string) {
+	imported := map[string]bool{file.GetName(): true}
+	use := func(name string) {
+		if name != "" && !imported[name] {
+			imported[name] = true
+			file.Dependency = append(file.Dependency, name)
+		}
+	}
+	useType := func(typeName string) {
+		name := strings.TrimPrefix(typeName, ".")
+		if topLevel := s.topLevelType(name); topLevel != "" {
+			name = s.fd.GetPackage() + "." + topLevel
+		}
+		use(definitions[name])
+	}
+
+	var visit func(messages []*dpb.DescriptorProto)
+	visit = func(messages []*dpb.DescriptorProto) {
+		for _, message := range messages {
+			for _, field := range message.Field {
+				useType(field.GetTypeName())
+				for _, dependency := range getFieldDependencies(field) {
+					use(dependency.GetName())
+				}
+			}
+			visit(message.NestedType)
+		}
+	}
+	visit(file.MessageType)
+
+	for _, service := range file.Service {
+		for _, method := range service.Method {
+			useType(method.GetInputType())
+			useType(method.GetOutputType())
+			if method.Options != nil && proto.HasExtension(method.Options, annotations.E_Http) {
+				use("google/api/annotations.proto")
+			}
+			if method.Options != nil && proto.HasExtension(method.Options, metadata.E_Metadata) {
+				use(metadata.E_Metadata.Filename)
+			}
+		}
+	}
+}
+
+// Adds the fully qualified names of all messages and enums of 'file' (including nested ones) to 'definitions'.
+func addTypeDefinitions(definitions map[string]string, file *dpb.FileDescriptorProto) {
+	prefix := ""
+	if file.GetPackage() != "" {
+		prefix = file.GetPackage() + "."
+	}
+	var visit func(prefix string, messages []*dpb.DescriptorProto, enums []*dpb.EnumDescriptorProto)
+	visit = func(prefix string, messages []*dpb.DescriptorProto, enums []*dpb.EnumDescriptorProto) {
+		for _, message := range messages {
+			definitions[prefix+message.GetName()] = file.GetName()
+			visit(prefix+message.GetName()+".", message.NestedType, message.EnumType)
+		}
+		for _, enum := range enums {
+			definitions[prefix+enum.GetName()] = file.GetName()
+		}
+	}
+	visit(prefix, file.MessageType, file.EnumType)
+}
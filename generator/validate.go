@@ -0,0 +1,184 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"math"
+
+	"github.com/envoyproxy/protoc-gen-validate/validate"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	openapiv3 "github.com/googleapis/gnostic/OpenAPIv3"
+)
+
+// Returns the protoc-gen-validate rules (https://github.com/envoyproxy/protoc-gen-validate) that enforce the
+// constraints of 'schema' for the field 'fd' or nil if there are none. Arrays get the rules 'min_items', 'max_items'
+// and 'unique', the constraints of their items are applied to every item. protoc-gen-validate has no rule for
+// 'multipleOf', so it is not validated and the checker reports it.
+func buildValidateRules(fd *dpb.FieldDescriptorProto, schema *openapiv3.Schema) *validate.FieldRules {
+	if schema == nil {
+		return nil
+	}
+	if fd.GetLabel() != dpb.FieldDescriptorProto_LABEL_REPEATED {
+		return buildScalarValidateRules(fd.GetType(), schema)
+	}
+
+	// Maps are repeated fields as well, but they do not have an array schema.
+	if schema.Type != "array" {
+		return nil
+	}
+	rules := &validate.RepeatedRules{}
+	if schema.MinItems != 0 {
+		rules.MinItems = proto.Uint64(uint64(schema.MinItems))
+	}
+	if schema.MaxItems != 0 {
+		rules.MaxItems = proto.Uint64(uint64(schema.MaxItems))
+	}
+	// protoc-gen-validate only supports uniqueness for scalars and enums.
+	if schema.UniqueItems && fd.GetType() != dpb.FieldDescriptorProto_TYPE_MESSAGE {
+		rules.Unique = proto.Bool(true)
+	}
+	if schema.Items != nil && len(schema.Items.SchemaOrReference) > 0 {
+		rules.Items = buildScalarValidateRules(fd.GetType(), schema.Items.SchemaOrReference[0].GetSchema())
+	}
+	if proto.Equal(rules, &validate.RepeatedRules{}) {
+		return nil
+	}
+	return &validate.FieldRules{Type: &validate.FieldRules_Repeated{Repeated: rules}}
+}
+
+// Returns the rules for a single value of the type 'protoType' or nil if 'schema' does not constrain it. Strings get
+// 'min_len', 'max_len' and 'pattern', numbers get 'gt', 'gte', 'lt' and 'lte'.
+func buildScalarValidateRules(protoType dpb.FieldDescriptorProto_Type, schema *openapiv3.Schema) *validate.FieldRules {
+	if schema == nil {
+		return nil
+	}
+
+	if protoType == dpb.FieldDescriptorProto_TYPE_STRING {
+		rules := &validate.StringRules{}
+		if schema.MinLength != 0 {
+			rules.MinLen = proto.Uint64(uint64(schema.MinLength))
+		}
+		if schema.MaxLength != 0 {
+			rules.MaxLen = proto.Uint64(uint64(schema.MaxLength))
+		}
+		if schema.Pattern != "" {
+			rules.Pattern = proto.String(schema.Pattern)
+		}
+		if proto.Equal(rules, &validate.StringRules{}) {
+			return nil
+		}
+		return &validate.FieldRules{Type: &validate.FieldRules_String_{String_: rules}}
+	}
+
+	// The OpenAPI description is read into a proto3 message, so a bound of 0 cannot be told apart from a missing one.
+	// An exclusive bound is always set though, so 'exclusiveMinimum: true' with a missing minimum is read as 'gt: 0'.
+	if schema.Minimum == 0 && !schema.ExclusiveMinimum && schema.Maximum == 0 && !schema.ExclusiveMaximum {
+		return nil
+	}
+	switch protoType {
+	case dpb.FieldDescriptorProto_TYPE_DOUBLE:
+		rules := &validate.DoubleRules{}
+		applyNumericBounds(schema, false,
+			func(v float64) { rules.Gt = proto.Float64(v) },
+			func(v float64) { rules.Gte = proto.Float64(v) },
+			func(v float64) { rules.Lt = proto.Float64(v) },
+			func(v float64) { rules.Lte = proto.Float64(v) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Double{Double: rules}}
+	case dpb.FieldDescriptorProto_TYPE_FLOAT:
+		rules := &validate.FloatRules{}
+		applyNumericBounds(schema, false,
+			func(v float64) { rules.Gt = proto.Float32(float32(v)) },
+			func(v float64) { rules.Gte = proto.Float32(float32(v)) },
+			func(v float64) { rules.Lt = proto.Float32(float32(v)) },
+			func(v float64) { rules.Lte = proto.Float32(float32(v)) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Float{Float: rules}}
+	case dpb.FieldDescriptorProto_TYPE_INT64:
+		rules := &validate.Int64Rules{}
+		applyNumericBounds(schema, true,
+			func(v float64) { rules.Gt = proto.Int64(int64(v)) },
+			func(v float64) { rules.Gte = proto.Int64(int64(v)) },
+			func(v float64) { rules.Lt = proto.Int64(int64(v)) },
+			func(v float64) { rules.Lte = proto.Int64(int64(v)) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Int64{Int64: rules}}
+	case dpb.FieldDescriptorProto_TYPE_INT32:
+		rules := &validate.Int32Rules{}
+		applyNumericBounds(schema, true,
+			func(v float64) { rules.Gt = proto.Int32(int32(v)) },
+			func(v float64) { rules.Gte = proto.Int32(int32(v)) },
+			func(v float64) { rules.Lt = proto.Int32(int32(v)) },
+			func(v float64) { rules.Lte = proto.Int32(int32(v)) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Int32{Int32: rules}}
+	case dpb.FieldDescriptorProto_TYPE_UINT64:
+		rules := &validate.UInt64Rules{}
+		applyNumericBounds(schema, true,
+			func(v float64) { rules.Gt = proto.Uint64(uint64(v)) },
+			func(v float64) { rules.Gte = proto.Uint64(uint64(v)) },
+			func(v float64) { rules.Lt = proto.Uint64(uint64(v)) },
+			func(v float64) { rules.Lte = proto.Uint64(uint64(v)) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Uint64{Uint64: rules}}
+	case dpb.FieldDescriptorProto_TYPE_UINT32:
+		rules := &validate.UInt32Rules{}
+		applyNumericBounds(schema, true,
+			func(v float64) { rules.Gt = proto.Uint32(uint32(v)) },
+			func(v float64) { rules.Gte = proto.Uint32(uint32(v)) },
+			func(v float64) { rules.Lt = proto.Uint32(uint32(v)) },
+			func(v float64) { rules.Lte = proto.Uint32(uint32(v)) })
+		return &validate.FieldRules{Type: &validate.FieldRules_Uint32{Uint32: rules}}
+	}
+	return nil
+}
+
+// Calls the setter of the rule that corresponds to 'minimum' (together with 'exclusiveMinimum') and 'maximum'
+// (together with 'exclusiveMaximum') of 'schema'. A bound of 0 is only applied if it is exclusive. If the field is an
+// 'integer', fractional bounds are rounded towards the allowed values and become inclusive (e.g.: 'gte: 2' for
+// 'minimum: 1.5' and 'lte: 2' for 'exclusiveMaximum: 2.5'), since the integer rules cannot hold fractions.
+func applyNumericBounds(schema *openapiv3.Schema, integer bool, gt, gte, lt, lte func(float64)) {
+	if schema.Minimum != 0 || schema.ExclusiveMinimum {
+		if integer && schema.Minimum != math.Ceil(schema.Minimum) {
+			gte(math.Ceil(schema.Minimum))
+		} else if schema.ExclusiveMinimum {
+			gt(schema.Minimum)
+		} else {
+			gte(schema.Minimum)
+		}
+	}
+	if schema.Maximum != 0 || schema.ExclusiveMaximum {
+		if integer && schema.Maximum != math.Floor(schema.Maximum) {
+			lte(math.Floor(schema.Maximum))
+		} else if schema.ExclusiveMaximum {
+			lt(schema.Maximum)
+		} else {
+			lte(schema.Maximum)
+		}
+	}
+}
+
+// Sets the option '(validate.rules)' of 'fd'.
+func setValidateRules(fd *dpb.FieldDescriptorProto, rules *validate.FieldRules) error {
+	if rules == nil {
+		return nil
+	}
+	if fd.Options == nil {
+		fd.Options = &dpb.FieldOptions{}
+	}
+	return proto.SetExtension(fd.Options, validate.E_Rules, rules)
+}
+
+// Returns the FileDescriptorProto of validate/validate.proto.
+func getValidateFile() *dpb.FileDescriptorProto {
+	fd, _ := decompressFileDescriptor(proto.FileDescriptor(validate.E_Rules.Filename))
+	return fd
+}
@@ -0,0 +1,194 @@
+// Copyright 2019 Google Inc. All Rights Reserved.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package generator
+
+import (
+	"github.com/envoyproxy/protoc-gen-validate/validate"
+	"github.com/golang/protobuf/descriptor"
+	"github.com/golang/protobuf/proto"
+	dpb "github.com/golang/protobuf/protoc-gen-go/descriptor"
+	"github.com/golang/protobuf/ptypes/duration"
+	structpb "github.com/golang/protobuf/ptypes/struct"
+	"github.com/golang/protobuf/ptypes/timestamp"
+	"github.com/golang/protobuf/ptypes/wrappers"
+	"github.com/googleapis/gnostic-grpc/metadata"
+	surface_v1 "github.com/googleapis/gnostic/surface"
+	"google.golang.org/genproto/googleapis/api/annotations"
+	"google.golang.org/genproto/googleapis/api/httpbody"
+	"google.golang.org/genproto/googleapis/type/date"
+)
+
+// Well-known types that are used for strings with one of the following formats (if Renderer.TimeTypes is set).
+var timeFormatTypes = map[string]string{
+	"date-time": "google.protobuf.Timestamp",
+	"date":      "google.type.Date",
+	"duration":  "google.protobuf.Duration",
+}
+
+// Messages of well-known types by their fully qualified name. The files that define them are only added as dependency
+// if a generated field actually uses them.
+var wellKnownTypes = map[string]descriptor.Message{
+	"google.protobuf.Timestamp": &timestamp.Timestamp{},
+	"google.protobuf.Duration":  &duration.Duration{},
+	"google.type.Date":          &date.Date{},
+	// Free-form objects and values.
+	"google.protobuf.Struct":    &structpb.Struct{},
+	"google.protobuf.Value":     &structpb.Value{},
+	"google.protobuf.ListValue": &structpb.ListValue{},
+	// Binary request and response bodies.
+	"google.api.HttpBody": &httpbody.HttpBody{},
+	// Wrapper types for optional fields.
+	"google.protobuf.DoubleValue": &wrappers.DoubleValue{},
+	"google.protobuf.FloatValue":  &wrappers.FloatValue{},
+	"google.protobuf.Int64Value":  &wrappers.Int64Value{},
+	"google.protobuf.UInt64Value": &wrappers.UInt64Value{},
+	"google.protobuf.Int32Value":  &wrappers.Int32Value{},
+	"google.protobuf.UInt32Value": &wrappers.UInt32Value{},
+	"google.protobuf.BoolValue":   &wrappers.BoolValue{},
+	"google.protobuf.StringValue": &wrappers.StringValue{},
+	"google.protobuf.BytesValue":  &wrappers.BytesValue{},
+}
+
+// Returns the well-known type for the string field 'f' or false if its format does not have one.
+func getTimeFormatType(f *surface_v1.Field) (string, bool) {
+	if f.Type != "string" || (f.Kind != surface_v1.FieldKind_SCALAR && f.Kind != surface_v1.FieldKind_ARRAY) {
+		return "", false
+	}
+	typeName, ok := timeFormatTypes[f.Format]
+	return typeName, ok
+}
+
+// Changes 'fd' into a field that has the message 'typeName' as type.
+func setFieldDescriptorMessageType(fd *dpb.FieldDescriptorProto, typeName string) {
+	protoType := dpb.FieldDescriptorProto_TYPE_MESSAGE
+	fd.Type = &protoType
+	fd.TypeName = &typeName
+}
+
+// Changes 'fd' into a field that has the well-known type 'typeName' as type. A list of google.protobuf.Value is
+// represented by google.protobuf.ListValue.
+func setFieldDescriptorWellKnownType(fd *dpb.FieldDescriptorProto, typeName string) {
+	if typeName == "google.protobuf.Value" && fd.GetLabel() == dpb.FieldDescriptorProto_LABEL_REPEATED {
+		label := dpb.FieldDescriptorProto_LABEL_OPTIONAL
+		fd.Label = &label
+		typeName = "google.protobuf.ListValue"
+	}
+	setFieldDescriptorMessageType(fd, typeName)
+}
+
+// Adds the files of all well-known types and annotations that are used inside of 'fd' to 'fdSet' and imports them
+// inside of 'fd'. 'fd' has to be the last file of the set. The files are imported in the order of their first usage.
+func buildWellKnownDependencies(fdSet *dpb.FileDescriptorSet, fd *dpb.FileDescriptorProto) {
+	present := make(map[string]bool)
+	for _, file := range fdSet.File {
+		present[file.GetName()] = true
+	}
+
+	// The dependencies of an added file have to be part of the set as well (e.g.: validate/validate.proto imports
+	// google/protobuf/duration.proto), but they are not imported by 'fd'.
+	var add func(file *dpb.FileDescriptorProto)
+	add = func(file *dpb.FileDescriptorProto) {
+		if present[file.GetName()] {
+			return
+		}
+		present[file.GetName()] = true
+		for _, dependency := range file.Dependency {
+			if !present[dependency] {
+				if dependencyFile, err := decompressFileDescriptor(proto.FileDescriptor(dependency)); err == nil {
+					add(dependencyFile)
+				}
+			}
+		}
+		last := len(fdSet.File) - 1
+		fdSet.File = append(fdSet.File[:last], file, fdSet.File[last])
+	}
+
+	imported := make(map[string]bool)
+	use := func(file *dpb.FileDescriptorProto) {
+		add(file)
+		if !imported[file.GetName()] {
+			imported[file.GetName()] = true
+			fd.Dependency = append(fd.Dependency, file.GetName())
+		}
+	}
+
+	var visit func(messages []*dpb.DescriptorProto)
+	visit = func(messages []*dpb.DescriptorProto) {
+		for _, message := range messages {
+			for _, field := range message.Field {
+				for _, file := range getFieldDependencies(field) {
+					use(file)
+				}
+			}
+			visit(message.NestedType)
+		}
+	}
+	visit(fd.MessageType)
+
+	// Methods might use well-known types directly (e.g.: google.api.HttpBody).
+	for _, service := range fd.Service {
+		for _, method := range service.Method {
+			for _, typeName := range []string{method.GetInputType(), method.GetOutputType()} {
+				if m, ok := wellKnownTypes[typeName]; ok {
+					file, _ := descriptor.ForMessage(m)
+					use(file)
+				}
+			}
+			if method.Options != nil && proto.HasExtension(method.Options, metadata.E_Metadata) {
+				use(getMetadataFile())
+			}
+		}
+	}
+}
+
+// Returns the files of the well-known type and of the annotations 'field' uses.
+func getFieldDependencies(field *dpb.FieldDescriptorProto) []*dpb.FileDescriptorProto {
+	files := make([]*dpb.FileDescriptorProto, 0)
+	if m, ok := wellKnownTypes[field.GetTypeName()]; ok {
+		file, _ := descriptor.ForMessage(m)
+		files = append(files, file)
+	}
+	if field.Options != nil && proto.HasExtension(field.Options, annotations.E_FieldBehavior) {
+		files = append(files, getFieldBehaviorFile())
+	}
+	if field.Options != nil && proto.HasExtension(field.Options, validate.E_Rules) {
+		files = append(files, getValidateFile())
+	}
+	return files
+}
+
+// Returns true if 'name' is one of the files the generated files depend on: the files of gRPC-HTTP transcoding and
+// google.protobuf.Empty, which are always imported, and the files of well-known types and annotations.
+func isDependencyFile(name string) bool {
+	switch name {
+	case "google/api/annotations.proto", "google/api/http.proto", "google/protobuf/empty.proto", "google/protobuf/descriptor.proto":
+		return true
+	}
+	return isWellKnownTypeFile(name)
+}
+
+// Returns true if 'name' is the file of a well-known type or of an annotation. Those files are only imported if
+// they are used.
+func isWellKnownTypeFile(name string) bool {
+	if name == annotations.E_FieldBehavior.Filename || name == validate.E_Rules.Filename || name == metadata.E_Metadata.Filename {
+		return true
+	}
+	for _, m := range wellKnownTypes {
+		if file, _ := descriptor.ForMessage(m); file.GetName() == name {
+			return true
+		}
+	}
+	return false
+}
@@ -15,9 +15,15 @@
 package main
 
 import (
+	"os"
+
 	"github.com/googleapis/gnostic-grpc/generator"
 )
 
 func main() {
+	// 'gnostic-grpc breaking -old=... -new=...' compares two versions of an API instead of running as a plugin.
+	if len(os.Args) > 1 && os.Args[1] == "breaking" {
+		os.Exit(generator.RunBreakingChangeDetector(os.Args[2:], os.Stdout, os.Stderr))
+	}
 	generator.RunProtoGenerator()
 }